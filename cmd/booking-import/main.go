@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"spacetrouble/internal/pkg/booking"
+	"spacetrouble/internal/pkg/config"
+	"spacetrouble/internal/pkg/data/postgres"
+	"spacetrouble/internal/pkg/entity"
+	"spacetrouble/internal/pkg/wiring"
+)
+
+// importActor is the actor of the history of the imported bookings
+const importActor = "import"
+
+// booking-import books every row of a partner manifest and prints the report as json.
+//
+//	booking-import -format csv manifest.csv
+func main() {
+	format := flag.String("format", "", "csv or json, guessed from the file extension when empty")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: booking-import [-format csv|json] FILE")
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	report, err := run(ctx, config.NewConfig(), path, *format)
+	if len(report.Rows) > 0 {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, cfg *config.Config, path, format string) (booking.ImportReport, error) {
+	var report booking.ImportReport
+	f, err := os.Open(path)
+	if err != nil {
+		return report, err
+	}
+	defer f.Close()
+
+	db, err := pgxpool.Connect(ctx, cfg.DSN())
+	if err != nil {
+		return report, err
+	}
+	defer db.Close()
+
+	bookSrv := wiring.NewBookingService(cfg, postgres.NewStore(db))
+	return booking.ImportBookings(entity.WithActor(ctx, importActor), bookSrv, f, format)
+}
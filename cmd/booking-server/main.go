@@ -13,8 +13,11 @@ import (
 	"spacetrouble/internal/pkg/booking"
 	"spacetrouble/internal/pkg/config"
 	"spacetrouble/internal/pkg/data/postgres"
+	"spacetrouble/internal/pkg/destination"
+	"spacetrouble/internal/pkg/entity"
+	"spacetrouble/internal/pkg/flight"
 	"spacetrouble/internal/pkg/health"
-	"spacetrouble/internal/pkg/spacex"
+	"spacetrouble/internal/pkg/wiring"
 	"spacetrouble/pkg/apiutils"
 )
 
@@ -40,7 +43,11 @@ func main() {
 }
 
 type serviceContainer struct {
+	idempotency apiutils.IdempotencyStore
+
 	bookSrv booking.BookingService
+	dstSrv  destination.DestinationService
+	fltSrv  flight.FlightService
 }
 
 func run(ctx context.Context, cfg *config.Config) (err error) {
@@ -53,12 +60,17 @@ func run(ctx context.Context, cfg *config.Config) (err error) {
 	}
 	defer db.Close()
 
-	store := postgres.NewStore(db)
-	spaceXClient := spacex.NewSpaceXClient(cfg.SpaceXUrl)
+	store := postgres.NewStore(db, postgres.WithPendingIdempotencyKeyTTL(cfg.IdempotencyPendingTTL))
 	srvC := serviceContainer{
-		bookSrv: booking.NewBookingService(store, spaceXClient),
+		idempotency: store,
+
+		bookSrv: wiring.NewBookingService(cfg, store),
+		dstSrv:  destination.NewDestinationService(store),
+		fltSrv:  flight.NewFlightService(store),
 	}
 
+	go sweepHolds(ctx, srvC.bookSrv, cfg.HoldSweepInterval)
+
 	router := setupRouter(ctx, srvC)
 
 	srv := &http.Server{
@@ -100,6 +112,36 @@ func run(ctx context.Context, cfg *config.Config) (err error) {
 	return
 }
 
+// sweeperActor is recorded in the booking history for the holds expired by the sweeper.
+const sweeperActor = "hold-sweeper"
+
+// sweepHolds expires the holds that ran out and retries the failed refunds every interval until ctx is done.
+func sweepHolds(ctx context.Context, srv booking.BookingService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := srv.ExpireHolds(entity.WithActor(ctx, sweeperActor))
+			if err != nil {
+				fmt.Println("expiring holds:", err)
+			}
+			if n > 0 {
+				fmt.Printf("expired %d holds\n", n)
+			}
+			n, err = srv.RetryRefunds(ctx)
+			if err != nil {
+				fmt.Println("retrying refunds:", err)
+			}
+			if n > 0 {
+				fmt.Printf("refunded %d payments\n", n)
+			}
+		}
+	}
+}
+
 func setupRouter(ctx context.Context, srvC serviceContainer) http.Handler {
 	router := http.NewServeMux()
 
@@ -108,10 +150,83 @@ func setupRouter(ctx context.Context, srvC serviceContainer) http.Handler {
 	router.HandleFunc(versionPrefix+"/health", health.HealthGet())
 
 	bookingHandler := apiutils.AllowedMethods(
-		apiutils.AllowedContentTypes(booking.BookingHandler(srvC.bookSrv), "application/json"),
+		apiutils.AllowedContentTypes(
+			apiutils.Idempotent(booking.WithActor(booking.BookingHandler(srvC.bookSrv)), srvC.idempotency),
+			"application/json",
+		),
 		"POST", "GET",
 	)
 	router.HandleFunc(versionPrefix+"/bookings", bookingHandler)
 
+	groupBookingHandler := apiutils.AllowedMethods(
+		apiutils.AllowedContentTypes(
+			apiutils.Idempotent(booking.WithActor(booking.GroupBookingHandler(srvC.bookSrv)), srvC.idempotency),
+			"application/json",
+		),
+		"POST",
+	)
+	// the exact path wins over the /bookings/ prefix below
+	router.HandleFunc(versionPrefix+"/bookings/group", groupBookingHandler)
+
+	exportHandler := apiutils.AllowedMethods(booking.ExportHandler(srvC.bookSrv), "GET")
+	router.HandleFunc(versionPrefix+"/bookings/export", exportHandler)
+
+	importHandler := apiutils.AllowedMethods(
+		apiutils.AllowedContentTypes(booking.WithActor(booking.ImportHandler(srvC.bookSrv)), "application/json", "text/csv"),
+		"POST",
+	)
+	router.HandleFunc(versionPrefix+"/bookings/import", importHandler)
+
+	bookingItemHandler := apiutils.AllowedMethods(
+		apiutils.AllowedContentTypes(booking.WithActor(booking.BookingItemHandler(srvC.bookSrv)), "application/json"),
+		"GET", "POST", "PATCH", "DELETE",
+	)
+	router.Handle(versionPrefix+"/bookings/", http.StripPrefix(versionPrefix+"/bookings/", bookingItemHandler))
+
+	holdHandler := apiutils.AllowedMethods(
+		apiutils.AllowedContentTypes(
+			apiutils.Idempotent(booking.WithActor(booking.HoldHandler(srvC.bookSrv)), srvC.idempotency),
+			"application/json",
+		),
+		"POST",
+	)
+	router.HandleFunc(versionPrefix+"/holds", holdHandler)
+
+	waitlistItemHandler := apiutils.AllowedMethods(
+		apiutils.AllowedContentTypes(booking.WaitlistItemHandler(srvC.bookSrv), "application/json"),
+		"GET",
+	)
+	router.Handle(versionPrefix+"/waitlist/", http.StripPrefix(versionPrefix+"/waitlist/", waitlistItemHandler))
+
+	userItemHandler := apiutils.AllowedMethods(
+		apiutils.AllowedContentTypes(booking.UserItemHandler(srvC.bookSrv), "application/json"),
+		"GET",
+	)
+	router.Handle(versionPrefix+"/users/", http.StripPrefix(versionPrefix+"/users/", userItemHandler))
+
+	destinationHandler := apiutils.AllowedMethods(
+		apiutils.AllowedContentTypes(destination.DestinationHandler(srvC.dstSrv), "application/json"),
+		"POST", "GET",
+	)
+	router.HandleFunc(versionPrefix+"/destinations", destinationHandler)
+
+	destinationItemHandler := apiutils.AllowedMethods(
+		apiutils.AllowedContentTypes(destination.DestinationItemHandler(srvC.dstSrv), "application/json"),
+		"GET", "PATCH", "DELETE",
+	)
+	router.Handle(versionPrefix+"/destinations/", http.StripPrefix(versionPrefix+"/destinations/", destinationItemHandler))
+
+	quoteHandler := apiutils.AllowedMethods(
+		apiutils.AllowedContentTypes(booking.QuoteHandler(srvC.bookSrv), "application/json"),
+		"GET",
+	)
+	router.HandleFunc(versionPrefix+"/quotes", quoteHandler)
+
+	flightHandler := apiutils.AllowedMethods(
+		apiutils.AllowedContentTypes(flight.FlightHandler(srvC.fltSrv), "application/json"),
+		"GET",
+	)
+	router.HandleFunc(versionPrefix+"/flights", flightHandler)
+
 	return router
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"spacetrouble/internal/pkg/spacex/spacextest"
+)
+
+// fake-spacex serves a stand-in of the SpaceX v4 API under /v4, so that
+// SPACEX_URL=http://localhost:4000/v4 runs the bookings offline.
+//
+//	fake-spacex -addr :4000 -fixtures ./fixtures
+func main() {
+	addr := flag.String("addr", ":4000", "address to listen on")
+	dir := flag.String("fixtures", "", "directory of the <resource>.json fixtures, the bundled ones when empty")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := run(ctx, *addr, *dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, addr, dir string) error {
+	fixtures := spacextest.DefaultFixtures()
+	if dir != "" {
+		var err error
+		if fixtures, err = spacextest.LoadFixtures(dir); err != nil {
+			return err
+		}
+	}
+
+	router := http.NewServeMux()
+	router.Handle("/v4/", http.StripPrefix("/v4", spacextest.NewHandler(fixtures)))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           router,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	fmt.Println("serving the fake SpaceX API on", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"spacetrouble/internal/pkg/config"
+	"spacetrouble/internal/pkg/data/postgres"
+	"spacetrouble/internal/pkg/spacex"
+)
+
+// spacex-sync copies the launchpads and upcoming launches of the SpaceX API to postgres
+// every SPACEX_SYNC_INTERVAL, for the booking server running with SPACEX_SCHEDULE=true.
+//
+//	spacex-sync [-once]
+func main() {
+	once := flag.Bool("once", false, "sync once and exit, e.g. when run by a cron job")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := run(ctx, config.NewConfig(), *once); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, cfg *config.Config, once bool) error {
+	db, err := pgxpool.Connect(ctx, cfg.DSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	store := postgres.NewStore(db)
+	client := spacex.NewSpaceXClient(cfg.SpaceXUrl,
+		spacex.WithRetries(spacex.RetryPolicy{
+			MaxAttempts: cfg.SpaceXMaxAttempts,
+			BaseDelay:   cfg.SpaceXRetryBaseDelay,
+			MaxDelay:    cfg.SpaceXRetryMaxDelay,
+		}),
+	)
+
+	if err := syncSchedule(ctx, client, store); err != nil {
+		if once {
+			return err
+		}
+		fmt.Println("syncing the spacex schedule:", err)
+	}
+	if once {
+		return nil
+	}
+
+	ticker := time.NewTicker(cfg.SpaceXSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			// a failed sync keeps the previous schedule until it goes stale
+			if err := syncSchedule(ctx, client, store); err != nil {
+				fmt.Println("syncing the spacex schedule:", err)
+			}
+		}
+	}
+}
+
+func syncSchedule(ctx context.Context, client *spacex.SpaceXClient, store *postgres.Store) error {
+	s, err := spacex.SyncSchedule(ctx, client, store, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("synced %d launchpads and %d upcoming launches\n", s.Launchpads, s.Launches)
+	return nil
+}
@@ -1,9 +1,15 @@
 package booking
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"spacetrouble/internal/pkg/entity"
 	"spacetrouble/pkg/apiutils"
 )
 
@@ -17,6 +23,60 @@ func BookingHandler(srv BookingService) http.HandlerFunc {
 	}
 }
 
+// BookingItemHandler serves the routes under /bookings/{id}.
+// It expects the /bookings/ prefix to be already stripped from the path.
+func BookingItemHandler(srv BookingService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, action := splitItemPath(r.URL.Path)
+		switch {
+		case action == "" && r.Method == http.MethodGet:
+			get(srv, id, w, r)
+		case action == "" && r.Method == http.MethodDelete:
+			cancel(srv, id, w, r)
+		case action == "" && r.Method == http.MethodPatch:
+			rebook(srv, id, w, r)
+		case action == "cancel" && r.Method == http.MethodPost:
+			cancel(srv, id, w, r)
+		case action == "confirm" && r.Method == http.MethodPost:
+			confirm(srv, id, w, r)
+		case action == "history" && r.Method == http.MethodGet:
+			history(srv, id, w, r)
+		default:
+			apiutils.RenderResponse(r, w, http.StatusNotFound, nil)
+		}
+	}
+}
+
+// ActorHeader names who makes the request, e.g. a customer care agent,
+// it is recorded in the booking history.
+const ActorHeader = "X-Actor"
+
+// DefaultActor is recorded in the booking history for requests without ActorHeader.
+const DefaultActor = "api"
+
+// WithActor passes the actor of the request down to the store.
+func WithActor(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor := r.Header.Get(ActorHeader)
+		if actor == "" {
+			actor = DefaultActor
+		}
+		if len(actor) > 100 {
+			actor = actor[:100]
+		}
+		next.ServeHTTP(w, r.WithContext(entity.WithActor(r.Context(), actor)))
+	}
+}
+
+func splitItemPath(p string) (id string, action string) {
+	parts := strings.SplitN(strings.Trim(p, "/"), "/", 2)
+	id = parts[0]
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	return
+}
+
 func create(srv BookingService, w http.ResponseWriter, r *http.Request) {
 	var bookReq BookingRequest
 	if err := apiutils.JsonDecodeBody(r, &bookReq); err != nil {
@@ -32,6 +92,10 @@ func create(srv BookingService, w http.ResponseWriter, r *http.Request) {
 	}
 
 	ans, err := srv.MakeBooking(r.Context(), bookReq)
+	if bookReq.Waitlist && CanWaitlist(err) {
+		waitlist(srv, bookReq, w, r)
+		return
+	}
 	if err != nil {
 		ae := getApiError(err)
 		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
@@ -41,26 +105,222 @@ func create(srv BookingService, w http.ResponseWriter, r *http.Request) {
 	apiutils.RenderResponse(r, w, http.StatusCreated, ans)
 }
 
+// GroupBookingHandler serves POST /bookings/group.
+func GroupBookingHandler(srv BookingService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			apiutils.RenderResponse(r, w, http.StatusNotFound, nil)
+			return
+		}
+		var groupReq GroupBookingRequest
+		if err := apiutils.JsonDecodeBody(r, &groupReq); err != nil {
+			ae := apiutils.NewBadRequest("error json decoding body")
+			apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		if err := groupReq.Validate(); err != nil {
+			ae := apiutils.NewBadRequest(err.Error())
+			apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		ans, err := srv.MakeGroupBooking(r.Context(), groupReq)
+		if err != nil {
+			ae := getApiError(err)
+			apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		apiutils.RenderResponse(r, w, http.StatusCreated, ans)
+	}
+}
+
+// QuoteHandler serves GET /quotes, the price of a booking before booking it.
+func QuoteHandler(srv BookingService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			apiutils.RenderResponse(r, w, http.StatusNotFound, nil)
+			return
+		}
+		quoteReq, err := parseQuoteRequest(r.URL.Query())
+		if err != nil {
+			ae := apiutils.NewBadRequest(err.Error())
+			apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		if err := quoteReq.Validate(); err != nil {
+			ae := apiutils.NewBadRequest(err.Error())
+			apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		ans, err := srv.Quote(r.Context(), quoteReq)
+		if err != nil {
+			ae := getApiError(err)
+			apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		apiutils.RenderResponse(r, w, http.StatusOK, ans)
+	}
+}
+
+func parseQuoteRequest(query url.Values) (QuoteRequest, error) {
+	var err error
+	ans := QuoteRequest{
+		PassengerID:   query.Get("passenger"),
+		LaunchpadID:   query.Get("launchpad"),
+		DestinationID: query.Get("destination"),
+	}
+	if v := query.Get("date"); v != "" {
+		if ans.LaunchDate.Time, err = time.Parse(dateLayoutFmt, v); err != nil {
+			return ans, err
+		}
+	}
+	if v := query.Get("birthday"); v != "" {
+		if ans.Birthday.Time, err = time.Parse(dateLayoutFmt, v); err != nil {
+			return ans, err
+		}
+	}
+	return ans, nil
+}
+
+// HoldHandler serves POST /holds, reserving a seat until the hold is confirmed or expires.
+func HoldHandler(srv BookingService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			apiutils.RenderResponse(r, w, http.StatusNotFound, nil)
+			return
+		}
+		var holdReq HoldRequest
+		if err := apiutils.JsonDecodeBody(r, &holdReq); err != nil {
+			ae := apiutils.NewBadRequest("error json decoding body")
+			apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		if err := holdReq.Validate(); err != nil {
+			ae := apiutils.NewBadRequest(err.Error())
+			apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		ans, err := srv.HoldBooking(r.Context(), holdReq)
+		if err != nil {
+			ae := getApiError(err)
+			apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		apiutils.RenderResponse(r, w, http.StatusCreated, ans)
+	}
+}
+
+func rebook(srv BookingService, id string, w http.ResponseWriter, r *http.Request) {
+	var rebookReq RebookRequest
+	if err := apiutils.JsonDecodeBody(r, &rebookReq); err != nil {
+		ae := apiutils.NewBadRequest("error json decoding body")
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	if err := rebookReq.Validate(); err != nil {
+		ae := apiutils.NewBadRequest(err.Error())
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	ans, err := srv.RebookBooking(r.Context(), id, rebookReq)
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusOK, ans)
+}
+
+func history(srv BookingService, id string, w http.ResponseWriter, r *http.Request) {
+	ans, err := srv.BookingHistory(r.Context(), id)
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusOK, ans)
+}
+
+func confirm(srv BookingService, id string, w http.ResponseWriter, r *http.Request) {
+	ans, err := srv.ConfirmBooking(r.Context(), id)
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusOK, ans)
+}
+
+func waitlist(srv BookingService, bookReq BookingRequest, w http.ResponseWriter, r *http.Request) {
+	ans, err := srv.JoinWaitlist(r.Context(), bookReq)
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusAccepted, ans)
+}
+
+// WaitlistItemHandler serves GET /waitlist/{id}.
+// It expects the /waitlist/ prefix to be already stripped from the path.
+func WaitlistItemHandler(srv BookingService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, action := splitItemPath(r.URL.Path)
+		if action != "" || r.Method != http.MethodGet {
+			apiutils.RenderResponse(r, w, http.StatusNotFound, nil)
+			return
+		}
+		ans, err := srv.GetWaitlistEntry(r.Context(), id)
+		if err != nil {
+			ae := getApiError(err)
+			apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		apiutils.RenderResponse(r, w, http.StatusOK, ans)
+	}
+}
+
 func all(srv BookingService, w http.ResponseWriter, r *http.Request) {
+	getReq, err := parseGetBookingsReq(r)
+	if err != nil {
+		ae := apiutils.NewBadRequest(err.Error())
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	ans, err := srv.AllBookings(r.Context(), getReq)
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	apiutils.RenderResponse(r, w, http.StatusOK, ans)
+}
+
+func parseGetBookingsReq(r *http.Request) (GetBookingsReq, error) {
+	query := r.URL.Query()
 	var limit int
-	if keys, ok := r.URL.Query()["limit"]; ok {
+	if keys, ok := query["limit"]; ok {
 		if len(keys) > 0 && len(keys[0]) > 0 {
 			var err error
 			limit, err = strconv.Atoi(keys[0])
 			if err != nil {
-				ae := apiutils.NewBadRequest(err.Error())
-				apiutils.RenderResponse(r, w, ae.StatusCode, ae)
-				return
+				return GetBookingsReq{}, err
 			}
 			if limit < 0 {
-				ae := apiutils.NewBadRequest("negative limit")
-				apiutils.RenderResponse(r, w, ae.StatusCode, ae)
-				return
+				return GetBookingsReq{}, errors.New("negative limit")
 			}
 		}
 	}
 	var cur string
-	if keys, ok := r.URL.Query()["cursor"]; ok {
+	if keys, ok := query["cursor"]; ok {
 		if len(keys) > 0 && len(keys[0]) > 0 {
 			cur = keys[0]
 		}
@@ -69,19 +329,78 @@ func all(srv BookingService, w http.ResponseWriter, r *http.Request) {
 		limit = 10
 	}
 	getReq := GetBookingsReq{
-		Limit: limit,
+		Limit:         limit,
+		Cursor:        cur,
+		Status:        query.Get("status"),
+		DestinationID: query.Get("destination"),
+		LaunchpadID:   query.Get("launchpad"),
+		Passenger:     query.Get("passenger"),
+		Sort:          query.Get("sort"),
+		Total:         query.Get("total"),
 	}
-	if len(cur) > 0 {
-		var err error
-		getReq.Ts, getReq.Uuid, err = decodeCursor(cur)
-		if err != nil {
-			ae := apiutils.NewBadRequest(err.Error())
-			apiutils.RenderResponse(r, w, ae.StatusCode, ae)
-			return
+	// created_at is the default order, it is left empty so that cursors match either way
+	if getReq.Sort == entity.BookingSortCreatedAt {
+		getReq.Sort = ""
+	}
+	dates := []struct {
+		param string
+		dst   *time.Time
+	}{
+		{"from", &getReq.LaunchFrom},
+		{"to", &getReq.LaunchTo},
+		{"created_from", &getReq.CreatedFrom},
+		{"created_to", &getReq.CreatedTo},
+	}
+	for _, d := range dates {
+		if v := query.Get(d.param); v != "" {
+			t, err := time.Parse(dateLayoutFmt, v)
+			if err != nil {
+				return getReq, fmt.Errorf("invalid date for %s", d.param)
+			}
+			*d.dst = t
 		}
 	}
+	if err := getReq.Validate(); err != nil {
+		return getReq, err
+	}
+	return getReq, nil
+}
 
-	ans, err := srv.AllBookings(r.Context(), getReq)
+// UserItemHandler serves the routes under /users/{id}.
+// It expects the /users/ prefix to be already stripped from the path.
+func UserItemHandler(srv BookingService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, action := splitItemPath(r.URL.Path)
+		switch {
+		case action == "" && r.Method == http.MethodGet:
+			getPassenger(srv, id, w, r)
+		case action == "bookings" && r.Method == http.MethodGet:
+			passengerBookings(srv, id, w, r)
+		default:
+			apiutils.RenderResponse(r, w, http.StatusNotFound, nil)
+		}
+	}
+}
+
+func getPassenger(srv BookingService, id string, w http.ResponseWriter, r *http.Request) {
+	ans, err := srv.GetPassenger(r.Context(), id)
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusOK, ans)
+}
+
+func passengerBookings(srv BookingService, id string, w http.ResponseWriter, r *http.Request) {
+	getReq, err := parseGetBookingsReq(r)
+	if err != nil {
+		ae := apiutils.NewBadRequest(err.Error())
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	ans, err := srv.PassengerBookings(r.Context(), id, getReq)
 	if err != nil {
 		ae := getApiError(err)
 		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
@@ -91,15 +410,53 @@ func all(srv BookingService, w http.ResponseWriter, r *http.Request) {
 	apiutils.RenderResponse(r, w, http.StatusOK, ans)
 }
 
+func get(srv BookingService, id string, w http.ResponseWriter, r *http.Request) {
+	ans, err := srv.GetBooking(r.Context(), id)
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusOK, ans)
+}
+
+func cancel(srv BookingService, id string, w http.ResponseWriter, r *http.Request) {
+	var cancelReq CancelBookingRequest
+	if err := apiutils.JsonDecodeOptionalBody(r, &cancelReq); err != nil {
+		ae := apiutils.NewBadRequest("error json decoding body")
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	if err := cancelReq.Validate(); err != nil {
+		ae := apiutils.NewBadRequest(err.Error())
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	ans, err := srv.CancelBooking(r.Context(), id, cancelReq)
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusOK, ans)
+}
+
 func getApiError(err error) apiutils.ApiError {
 	ae := apiutils.ApiError{Msg: err.Error()}
 	switch err {
-	case ErrInvalidUUID:
+	case ErrInvalidUUID, ErrInvalidCursor:
 		ae.StatusCode = http.StatusBadRequest
-	case ErrMissingDestination:
+	case ErrMissingDestination, ErrBookingNotFound, ErrWaitlistEntryNotFound, ErrPassengerNotFound:
 		ae.StatusCode = http.StatusNotFound
-	case ErrLaunchPadUnavailable:
+	case ErrLaunchPadUnavailable, ErrBookingNotCancelable, ErrFlightFull, ErrAlreadyBooked, ErrAlreadyWaiting,
+		ErrBookingNotConfirmable, ErrHoldExpired, ErrBookingNotModifiable:
 		ae.StatusCode = http.StatusConflict
+	case ErrPaymentFailed:
+		ae.StatusCode = http.StatusPaymentRequired
+	case ErrSpaceXUnavailable:
+		ae.StatusCode = http.StatusServiceUnavailable
 	default:
 		ae.StatusCode = http.StatusInternalServerError
 	}
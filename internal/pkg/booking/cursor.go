@@ -0,0 +1,113 @@
+package booking
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	cursorNext = "next"
+	cursorPrev = "prev"
+)
+
+var errInvalidSignature = errors.New("invalid cursor signature")
+
+// cursor points at the booking a page starts after, in Direction, for the
+// filters and sort order of the listing it was issued for.
+type cursor struct {
+	Direction string        `json:"d"`
+	Value     time.Time     `json:"v"`
+	ID        string        `json:"id"`
+	Filters   cursorFilters `json:"f"`
+}
+
+// cursorFilters are the filters of a listing, dates are kept formatted so that
+// the filters of a decoded cursor compare equal to the ones of the request.
+type cursorFilters struct {
+	UserID        string `json:"u,omitempty"`
+	Status        string `json:"st,omitempty"`
+	DestinationID string `json:"ds,omitempty"`
+	LaunchpadID   string `json:"lp,omitempty"`
+	LaunchFrom    string `json:"lf,omitempty"`
+	LaunchTo      string `json:"lt,omitempty"`
+	Passenger     string `json:"p,omitempty"`
+	CreatedFrom   string `json:"cf,omitempty"`
+	CreatedTo     string `json:"ct,omitempty"`
+	Sort          string `json:"s,omitempty"`
+}
+
+func newCursorFilters(userID string, req GetBookingsReq) cursorFilters {
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(dateLayoutFmt)
+	}
+	return cursorFilters{
+		UserID:        userID,
+		Status:        req.Status,
+		DestinationID: req.DestinationID,
+		LaunchpadID:   req.LaunchpadID,
+		LaunchFrom:    date(req.LaunchFrom),
+		LaunchTo:      date(req.LaunchTo),
+		Passenger:     req.Passenger,
+		CreatedFrom:   date(req.CreatedFrom),
+		CreatedTo:     date(req.CreatedTo),
+		Sort:          req.Sort,
+	}
+}
+
+// encodeCursor returns the cursor as base64 JSON followed by its HMAC-SHA256 signature.
+func encodeCursor(secret []byte, c cursor) string {
+	payload, _ := json.Marshal(c)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signCursor(secret, encoded))
+}
+
+func decodeCursor(secret []byte, s string) (cursor, error) {
+	var c cursor
+	encoded, sig, ok := strings.Cut(s, ".")
+	if !ok {
+		return c, errors.New("invalid cursor")
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return c, err
+	}
+	if !hmac.Equal(mac, signCursor(secret, encoded)) {
+		return c, errInvalidSignature
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return c, err
+	}
+	if c.Direction != cursorNext && c.Direction != cursorPrev {
+		return c, errors.New("invalid cursor direction")
+	}
+	return c, nil
+}
+
+func signCursor(secret []byte, encoded string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(encoded))
+	return h.Sum(nil)
+}
+
+// newCursorSecret is used when no secret is configured, cursors are then only
+// valid until the service restarts.
+func newCursorSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}
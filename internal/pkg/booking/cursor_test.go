@@ -0,0 +1,44 @@
+package booking
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorSignature(t *testing.T) {
+	secret := []byte("secret")
+	c := cursor{
+		Direction: cursorNext,
+		Value:     time.Date(2049, 4, 6, 10, 0, 0, 0, time.UTC),
+		ID:        "06539a98-ab56-4152-ba1a-c274f8fa87d8",
+		Filters:   cursorFilters{Status: "active", Sort: "-launch_date"},
+	}
+	encoded := encodeCursor(secret, c)
+	decoded, err := decodeCursor(secret, encoded)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decoded != c {
+		t.Errorf("expected %+v but got %+v", c, decoded)
+		return
+	}
+
+	payload, sig, _ := strings.Cut(encoded, ".")
+	raw, _ := base64.RawURLEncoding.DecodeString(payload)
+	forged := strings.Replace(string(raw), `"active"`, `"cancelled"`, 1)
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(forged)) + "." + sig
+	if _, err := decodeCursor(secret, tampered); err != errInvalidSignature {
+		t.Errorf("expected %v but got %v", errInvalidSignature, err)
+		return
+	}
+	if _, err := decodeCursor([]byte("other"), encoded); err != errInvalidSignature {
+		t.Errorf("expected %v but got %v", errInvalidSignature, err)
+		return
+	}
+	if _, err := decodeCursor(secret, payload); err == nil {
+		t.Errorf("expected an error for an unsigned cursor")
+	}
+}
@@ -5,7 +5,25 @@ import (
 )
 
 var (
-	ErrInvalidUUID          = errors.New("invalid uuid")
-	ErrMissingDestination   = errors.New("destination does not exist")
-	ErrLaunchPadUnavailable = errors.New("launchpad is unavailable")
+	ErrInvalidUUID           = errors.New("invalid uuid")
+	ErrMissingDestination    = errors.New("destination does not exist")
+	ErrLaunchPadUnavailable  = errors.New("launchpad is unavailable")
+	ErrBookingNotFound       = errors.New("booking does not exist")
+	ErrBookingNotCancelable  = errors.New("booking cannot be cancelled")
+	ErrFlightFull            = errors.New("flight is full")
+	ErrWaitlistEntryNotFound = errors.New("waitlist entry does not exist")
+	ErrPassengerNotFound     = errors.New("passenger does not exist")
+	ErrAlreadyBooked         = errors.New("passenger already booked on the flight")
+	ErrAlreadyWaiting        = errors.New("passenger already on the waitlist of the flight")
+	ErrBookingNotConfirmable = errors.New("booking is not awaiting confirmation")
+	ErrHoldExpired           = errors.New("hold has expired")
+	ErrPaymentFailed         = errors.New("payment failed")
+	ErrBookingNotModifiable  = errors.New("booking cannot be modified")
+	ErrInvalidCursor         = errors.New("invalid cursor")
+	ErrSpaceXUnavailable     = errors.New("spacex is unavailable, try again later")
 )
+
+// CanWaitlist tells whether a failed booking can be queued in the waitlist.
+func CanWaitlist(err error) bool {
+	return err == ErrLaunchPadUnavailable || err == ErrFlightFull
+}
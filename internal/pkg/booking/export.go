@@ -0,0 +1,166 @@
+package booking
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"spacetrouble/pkg/apiutils"
+)
+
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+
+	// exportFlushEvery is the number of bookings written between two flushes of the response
+	exportFlushEvery = 100
+)
+
+var csvHeader = []string{
+	"id", "status", "created_at", "cancelled_at", "cancel_reason", "expires_at", "price",
+	"passenger_id", "first_name", "last_name", "gender", "birthday",
+	"flight_id", "launchpad_id", "launch_date", "flight_status", "destination_id", "destination_name",
+}
+
+// exportInterrupted is the last line of an NDJSON export that failed after it started.
+type exportInterrupted struct {
+	Error string `json:"error"`
+}
+
+// ExportHandler serves GET /bookings/export, streaming every booking matching the
+// filters of the listing as CSV, the default, or NDJSON depending on the format parameter.
+// The export starts with the first booking, or once the query succeeded when there is none,
+// so that a failing query still answers an error status.
+func ExportHandler(srv BookingService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getReq, err := parseGetBookingsReq(r)
+		if err == nil {
+			err = validateExportFormat(r.URL.Query().Get("format"))
+		}
+		if err != nil {
+			ae := apiutils.NewBadRequest(err.Error())
+			renderJSON(w, ae.StatusCode, ae)
+			return
+		}
+
+		ndjson := r.URL.Query().Get("format") == ExportFormatNDJSON
+		var enc exportEncoder
+		start := func() {
+			if enc != nil {
+				return
+			}
+			if ndjson {
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				w.Header().Set("Content-Disposition", `attachment; filename="bookings.ndjson"`)
+				enc = &ndjsonEncoder{enc: json.NewEncoder(w)}
+			} else {
+				w.Header().Set("Content-Type", "text/csv")
+				w.Header().Set("Content-Disposition", `attachment; filename="bookings.csv"`)
+				enc = newCSVEncoder(w)
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+
+		flusher, _ := w.(http.Flusher)
+		n := 0
+		err = srv.ExportBookings(r.Context(), getReq, func(b BookingResponse) error {
+			start()
+			if err := enc.Encode(b); err != nil {
+				return err
+			}
+			n++
+			if n%exportFlushEvery == 0 {
+				if err := enc.Flush(); err != nil {
+					return err
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return nil
+		})
+		if err != nil && enc == nil {
+			log.Printf("exporting bookings: %v", err)
+			ae := getApiError(err)
+			renderJSON(w, ae.StatusCode, ae)
+			return
+		}
+		if err != nil {
+			log.Printf("exporting bookings, interrupted after %d: %v", n, err)
+			if ndjson {
+				_ = json.NewEncoder(w).Encode(exportInterrupted{Error: "export interrupted"})
+				return
+			}
+			// a CSV file has no room for an error, the response is aborted so that it is not taken for complete
+			_ = enc.Flush()
+			panic(http.ErrAbortHandler)
+		}
+		start()
+		_ = enc.Flush()
+	}
+}
+
+func validateExportFormat(format string) error {
+	if format != "" && format != ExportFormatCSV && format != ExportFormatNDJSON {
+		return errors.New("format must be csv or ndjson")
+	}
+	return nil
+}
+
+type exportEncoder interface {
+	Encode(b BookingResponse) error
+	Flush() error
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (o *ndjsonEncoder) Encode(b BookingResponse) error {
+	return o.enc.Encode(b)
+}
+
+func (o *ndjsonEncoder) Flush() error {
+	return nil
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+// newCSVEncoder writes the header, buffered until the first flush.
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	enc := csvEncoder{w: csv.NewWriter(w)}
+	_ = enc.w.Write(csvHeader)
+	return &enc
+}
+
+func (o *csvEncoder) Encode(b BookingResponse) error {
+	return o.w.Write(csvRecord(b))
+}
+
+func (o *csvEncoder) Flush() error {
+	o.w.Flush()
+	return o.w.Error()
+}
+
+func csvRecord(b BookingResponse) []string {
+	optTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+	return []string{
+		b.ID.String(), b.Status, b.CreatedAt.Format(time.RFC3339Nano), optTime(b.CancelledAt), b.CancelReason,
+		optTime(b.ExpiresAt), strconv.FormatInt(b.Price, 10),
+		b.User.ID.String(), b.User.FirstName, b.User.LastName, b.User.Gender, b.User.Birthday.Format(dateLayoutFmt),
+		b.Flight.ID.String(), b.Flight.LaunchpadID, b.Flight.Date.Format(dateLayoutFmt), b.Flight.Status,
+		b.Flight.Destination.ID.String(), b.Flight.Destination.Name,
+	}
+}
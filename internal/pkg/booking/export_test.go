@@ -0,0 +1,123 @@
+package booking
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"spacetrouble/internal/pkg/payment"
+)
+
+func TestExportHandler(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider())
+	var made []BookingResponse
+	for i := 0; i < 2; i++ {
+		b, err := srv.MakeBooking(context.Background(), newTestBookingRequest(availableDestinations[i].ID.String()))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		made = append(made, b)
+	}
+
+	export := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/bookings/export?"+query, nil)
+		rr := httptest.NewRecorder()
+		ExportHandler(srv)(rr, req)
+		return rr
+	}
+
+	rr := export("")
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected %v but got %v", http.StatusOK, rr.Code)
+		return
+	}
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(records) != 3 || records[0][0] != "id" || records[1][0] != made[0].ID.String() {
+		t.Errorf("expected a header and bookings %s, %s but got %v", made[0].ID, made[1].ID, records)
+		return
+	}
+
+	rr = export("format=ndjson&destination=" + availableDestinations[1].ID.String())
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if len(lines) != 1 {
+		t.Errorf("expected %v but got %v", 1, len(lines))
+		return
+	}
+	var b struct{ ID string }
+	if err := json.Unmarshal([]byte(lines[0]), &b); err != nil {
+		t.Error(err)
+		return
+	}
+	if b.ID != made[1].ID.String() {
+		t.Errorf("expected %v but got %v", made[1].ID, b.ID)
+		return
+	}
+
+	rr = export("format=xml")
+	if rr.Code != http.StatusBadRequest || rr.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected a json %v but got %v %v", http.StatusBadRequest, rr.Code, rr.Header().Get("Content-Type"))
+	}
+}
+
+// exportFailingSrv exports the bookings then fails.
+type exportFailingSrv struct {
+	BookingService
+	bookings []BookingResponse
+}
+
+func (o *exportFailingSrv) ExportBookings(ctx context.Context, req GetBookingsReq, fn func(BookingResponse) error) error {
+	for _, b := range o.bookings {
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return errors.New("connection reset")
+}
+
+func TestExportHandlerFailure(t *testing.T) {
+	export := func(srv BookingService, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/bookings/export?"+query, nil)
+		rr := httptest.NewRecorder()
+		ExportHandler(srv)(rr, req)
+		return rr
+	}
+
+	rr := export(&exportFailingSrv{}, "")
+	if rr.Code != http.StatusInternalServerError || rr.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected a json %v but got %v %v", http.StatusInternalServerError, rr.Code, rr.Header().Get("Content-Type"))
+		return
+	}
+
+	rr = export(&exportFailingSrv{bookings: []BookingResponse{{}}}, "format=ndjson")
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected %v but got %v", http.StatusOK, rr.Code)
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if len(lines) != 2 || lines[1] != `{"error":"export interrupted"}` {
+		t.Errorf("expected a booking and an error line but got %v", lines)
+	}
+}
@@ -0,0 +1,275 @@
+package booking
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"spacetrouble/pkg/apiutils"
+)
+
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatJSON = "json"
+
+	ImportRowCreated  = "created"
+	ImportRowRejected = "rejected"
+)
+
+// MaxImportBytes bounds the size of a manifest uploaded to the import endpoint.
+const MaxImportBytes = 10 << 20
+
+// ImportRequest is a row of a manifest. Without PayBy the passenger paid the partner
+// and the booking is active right away, otherwise it is pending payment until PayBy.
+type ImportRequest struct {
+	BookingRequest
+	PayBy *time.Time `json:",omitempty"`
+}
+
+func (o *ImportRequest) Validate() error {
+	if o.PayBy != nil && !o.PayBy.After(time.Now()) {
+		return errors.New("PayBy is in the past")
+	}
+	return o.BookingRequest.Validate()
+}
+
+// ImportRowResult is the outcome of a row, numbered from 1 without the CSV header.
+// BookingStatus and ExpiresAt are the ones of the created booking.
+type ImportRowResult struct {
+	Row           int        `json:"row"`
+	Status        string     `json:"status"`
+	BookingID     string     `json:"booking_id,omitempty"`
+	BookingStatus string     `json:"booking_status,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	Reason        string     `json:"reason,omitempty"`
+}
+
+// ImportReport lists the outcome of every row read. Aborted is the reason the
+// manifest could not be read past the last row.
+type ImportReport struct {
+	Created  int               `json:"created"`
+	Rejected int               `json:"rejected"`
+	Rows     []ImportRowResult `json:"rows"`
+	Aborted  string            `json:"aborted,omitempty"`
+}
+
+// rowError is a row that cannot be read, the rows after it still can.
+type rowError struct {
+	err error
+}
+
+func (o rowError) Error() string {
+	return o.err.Error()
+}
+
+// importReader returns the rows of a manifest one by one and io.EOF after the last one.
+type importReader interface {
+	Next() (ImportRequest, error)
+}
+
+// ImportBookings books every row of the manifest read from r, a CSV file with a header
+// or a JSON array of import requests. A row that does not validate or cannot be booked
+// is rejected with the reason and the import goes on with the next one. Only a manifest
+// that cannot be read any further, or the cancellation of ctx, aborts it.
+func ImportBookings(ctx context.Context, srv BookingService, r io.Reader, format string) (ImportReport, error) {
+	ans := ImportReport{Rows: make([]ImportRowResult, 0)}
+	var rows importReader
+	var err error
+	switch format {
+	case ImportFormatCSV:
+		rows, err = newCSVImportReader(r)
+	case ImportFormatJSON:
+		rows, err = newJSONImportReader(r)
+	default:
+		err = fmt.Errorf("unsupported import format %q", format)
+	}
+	if err != nil {
+		return ans, err
+	}
+	for i := 1; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return ans, err
+		}
+		req, err := rows.Next()
+		if err == io.EOF {
+			return ans, nil
+		}
+		var rowErr rowError
+		if err != nil && !errors.As(err, &rowErr) {
+			return ans, err
+		}
+		res := ImportRowResult{Row: i}
+		if err == nil {
+			err = req.Validate()
+		}
+		if err == nil {
+			var b BookingResponse
+			if b, err = srv.ImportBooking(ctx, req); err == nil {
+				res.BookingID, res.BookingStatus, res.ExpiresAt = b.ID.String(), b.Status, b.ExpiresAt
+			}
+		}
+		if err != nil {
+			res.Status, res.Reason = ImportRowRejected, err.Error()
+			ans.Rejected++
+		} else {
+			res.Status = ImportRowCreated
+			ans.Created++
+		}
+		ans.Rows = append(ans.Rows, res)
+	}
+}
+
+type csvImportReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+// newCSVImportReader reads the header of a manifest with the columns passenger_id, first_name,
+// last_name, gender, birthday, launchpad_id, destination_id, launch_date and pay_by in any order.
+// The passenger columns can be left out when passenger_id is set, pay_by is an RFC 3339 time.
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	ans := csvImportReader{r: csv.NewReader(r), columns: make(map[string]int)}
+	header, err := ans.r.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty manifest")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range header {
+		ans.columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	for _, name := range []string{"launchpad_id", "destination_id", "launch_date"} {
+		if _, ok := ans.columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %s", name)
+		}
+	}
+	return &ans, nil
+}
+
+func (o *csvImportReader) Next() (ImportRequest, error) {
+	var ans ImportRequest
+	record, err := o.r.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return ans, rowError{err}
+	}
+	if err != nil {
+		return ans, err
+	}
+	value := func(name string) string {
+		i, ok := o.columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	date := func(name string) (Date, error) {
+		var d Date
+		v := value(name)
+		if v == "" {
+			return d, nil
+		}
+		var err error
+		if d.Time, err = time.Parse(dateLayoutFmt, v); err != nil {
+			return d, rowError{fmt.Errorf("invalid %s", name)}
+		}
+		return d, nil
+	}
+	ans.BookingRequest = BookingRequest{
+		PassengerID:   value("passenger_id"),
+		FirstName:     value("first_name"),
+		LastName:      value("last_name"),
+		Gender:        value("gender"),
+		LaunchpadID:   value("launchpad_id"),
+		DestinationID: value("destination_id"),
+	}
+	if ans.Birthday, err = date("birthday"); err != nil {
+		return ans, err
+	}
+	if ans.LaunchDate, err = date("launch_date"); err != nil {
+		return ans, err
+	}
+	if v := value("pay_by"); v != "" {
+		payBy, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return ans, rowError{errors.New("invalid pay_by")}
+		}
+		ans.PayBy = &payBy
+	}
+	return ans, nil
+}
+
+type jsonImportReader struct {
+	dec *json.Decoder
+}
+
+func newJSONImportReader(r io.Reader) (*jsonImportReader, error) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("manifest must be a json array")
+	}
+	return &jsonImportReader{dec: dec}, nil
+}
+
+func (o *jsonImportReader) Next() (ImportRequest, error) {
+	var ans ImportRequest
+	if !o.dec.More() {
+		return ans, io.EOF
+	}
+	err := o.dec.Decode(&ans)
+	var syntaxErr *json.SyntaxError
+	if err != nil && !errors.As(err, &syntaxErr) && err != io.ErrUnexpectedEOF {
+		// the row was read in full but did not unmarshal
+		return ans, rowError{err}
+	}
+	return ans, err
+}
+
+// ImportHandler serves POST /bookings/import, the manifest is the body of the request, a CSV file
+// with the text/csv content type or a JSON array of import requests. The report is returned even when
+// rows are rejected, only a manifest that cannot be read at all returns 400.
+// The answer is json whatever the content type of the manifest.
+func ImportHandler(srv BookingService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			renderJSON(w, http.StatusNotFound, nil)
+			return
+		}
+		format := ImportFormatJSON
+		if r.Header.Get("Content-Type") == "text/csv" {
+			format = ImportFormatCSV
+		}
+		body := http.MaxBytesReader(w, r.Body, MaxImportBytes)
+		ans, err := ImportBookings(r.Context(), srv, body, format)
+		if err != nil && len(ans.Rows) == 0 {
+			ae := apiutils.NewBadRequest(err.Error())
+			renderJSON(w, ae.StatusCode, ae)
+			return
+		}
+		if err != nil {
+			ans.Aborted = err.Error()
+		}
+		renderJSON(w, http.StatusOK, ans)
+	}
+}
+
+// renderJSON writes res as json whatever the content type of the request,
+// apiutils.RenderResponse only answers json requests.
+func renderJSON(w http.ResponseWriter, statusCode int, res interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if res != nil {
+		_ = json.NewEncoder(w).Encode(res)
+	}
+}
@@ -0,0 +1,90 @@
+package booking
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"spacetrouble/internal/pkg/entity"
+	"spacetrouble/internal/pkg/payment"
+)
+
+func TestImportBookings(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider())
+	dest := availableDestinations[0].ID.String()
+	manifest := "first_name,last_name,gender,birthday,launchpad_id,destination_id,launch_date\n" +
+		"Anna,Papadopoulou,female,1990-01-01," + genLaunchId() + "," + dest + ",2049-04-06\n" +
+		"Maria,Papadopoulou,female,1990-01-01,short," + dest + ",2049-04-06\n" +
+		"Eleni,Papadopoulou,female,1990-01-01," + genLaunchId() + "," + dest + ",06/04/2049\n" +
+		"Nikos,Papadopoulos\n"
+
+	report, err := ImportBookings(context.Background(), srv, strings.NewReader(manifest), ImportFormatCSV)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if report.Created != 1 || report.Rejected != 3 || len(report.Rows) != 4 {
+		t.Errorf("expected 1 created and 3 rejected rows but got %+v", report)
+		return
+	}
+	if report.Rows[0].Status != ImportRowCreated || report.Rows[0].BookingID == "" ||
+		report.Rows[0].BookingStatus != entity.BookingStatusActive || report.Rows[0].ExpiresAt != nil {
+		t.Errorf("expected an active booking but got %+v", report.Rows[0])
+		return
+	}
+	if report.Rows[2].Reason != "invalid launch_date" {
+		t.Errorf("expected %v but got %v", "invalid launch_date", report.Rows[2].Reason)
+		return
+	}
+
+	// a json manifest with a row to be paid and a row that does not unmarshal
+	body := `[{"FirstName":"Anna","LastName":"Papadopoulou","Gender":"female","Birthday":"1990-01-01",` +
+		`"LaunchpadID":"` + genLaunchId() + `","DestinationID":"` + dest + `","Date":"2049-05-04",` +
+		`"PayBy":"2049-01-01T00:00:00Z"},` +
+		`{"FirstName":1}]`
+	req := httptest.NewRequest(http.MethodPost, "/bookings/import", strings.NewReader(body))
+	req.Header.Add("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	ImportHandler(srv)(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected %v but got %v", http.StatusOK, rr.Code)
+		return
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Error(err)
+		return
+	}
+	if report.Created != 1 || report.Rejected != 1 || report.Rows[1].Status != ImportRowRejected {
+		t.Errorf("expected 1 created and 1 rejected rows but got %+v", report)
+		return
+	}
+	if report.Rows[0].BookingStatus != entity.BookingStatusPendingPayment || report.Rows[0].ExpiresAt == nil ||
+		report.Rows[0].ExpiresAt.Year() != 2049 {
+		t.Errorf("expected a booking pending payment until 2049 but got %+v", report.Rows[0])
+		return
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/bookings/import", strings.NewReader("first_name\nAnna\n"))
+	req.Header.Add("Content-Type", "text/csv")
+	rr = httptest.NewRecorder()
+	ImportHandler(srv)(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected %v but got %v", http.StatusBadRequest, rr.Code)
+	}
+}
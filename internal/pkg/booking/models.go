@@ -1,7 +1,6 @@
 package booking
 
 import (
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,10 +11,25 @@ import (
 	"github.com/google/uuid"
 
 	"spacetrouble/internal/pkg/entity"
+	"spacetrouble/internal/pkg/pricing"
 )
 
 const (
 	dateLayoutFmt = "2006-01-02"
+
+	DefaultFlightCapacity = 10
+
+	DefaultHoldMinutes = 15
+	MaxHoldMinutes     = 60
+	// DefaultPaymentWindow is how long a booking waits for its payment unless configured otherwise.
+	DefaultPaymentWindow = 30 * time.Minute
+
+	MaxGroupSize = 10
+
+	// TotalExact and TotalEstimate ask for the total count of the bookings listed,
+	// the estimate is the one of the query planner.
+	TotalExact    = "exact"
+	TotalEstimate = "estimate"
 )
 
 type Date struct {
@@ -44,7 +58,10 @@ func (d Date) MarshalJSON() ([]byte, error) {
 }
 
 type BookingRequest struct {
-	ID            string `json:"id,omitempty"`
+	ID string `json:"id,omitempty"`
+	// PassengerID books a passenger known from previous bookings,
+	// the passenger details can be omitted then.
+	PassengerID   string `json:",omitempty"`
 	FirstName     string
 	LastName      string
 	Gender        string
@@ -52,6 +69,8 @@ type BookingRequest struct {
 	LaunchpadID   string
 	DestinationID string
 	LaunchDate    Date `json:"Date"`
+	// Waitlist queues the passenger when the flight is full or the launchpad unavailable
+	Waitlist bool `json:",omitempty"`
 }
 
 var lock *sync.RWMutex = &sync.RWMutex{}
@@ -62,11 +81,12 @@ var supportedGenders map[string]bool = map[string]bool{
 }
 
 func (o *BookingRequest) Validate() error {
-	if len(o.FirstName) == 0 || len(o.FirstName) > 50 {
-		return errors.New("FirstName must be more than 0 and less than 50 chars")
-	}
-	if len(o.LastName) == 0 || len(o.LastName) > 50 {
-		return errors.New("LastName must be more than 0 and less than 50 chars")
+	if o.PassengerID != "" {
+		if _, err := uuid.Parse(o.PassengerID); err != nil {
+			return errors.New("invalid uuid for PassengerID")
+		}
+	} else if err := validatePassenger(o.FirstName, o.LastName, o.Gender, o.Birthday); err != nil {
+		return err
 	}
 	if o.LaunchDate.IsZero() {
 		return errors.New("Date is empty")
@@ -74,27 +94,189 @@ func (o *BookingRequest) Validate() error {
 	if o.LaunchDate.Before(time.Now()) {
 		return errors.New("Date is in the past")
 	}
-	if o.Birthday.IsZero() {
+	if len(o.LaunchpadID) != 24 {
+		return errors.New("launchPadID must have length 24")
+	}
+	if _, err := uuid.Parse(o.DestinationID); err != nil {
+		return errors.New("invalid uuid for DestinationID")
+	}
+
+	return nil
+}
+
+func validatePassenger(firstName, lastName, gender string, birthday Date) error {
+	if len(firstName) == 0 || len(firstName) > 50 {
+		return errors.New("FirstName must be more than 0 and less than 50 chars")
+	}
+	if len(lastName) == 0 || len(lastName) > 50 {
+		return errors.New("LastName must be more than 0 and less than 50 chars")
+	}
+	if birthday.IsZero() {
 		return errors.New("empty Birthday")
 	}
 
-	if o.Birthday.After(time.Now()) {
+	if birthday.After(time.Now()) {
 		return errors.New("Birthday is in the past")
 	}
 
 	lock.RLock()
-	ok := supportedGenders[o.Gender]
+	ok := supportedGenders[gender]
 	lock.RUnlock()
 	if !ok {
 		return errors.New("invalid Gender")
 	}
-	if len(o.LaunchpadID) != 24 {
+	return nil
+}
+
+// GroupBookingRequest books several passengers on the same flight, all or none of them.
+type GroupBookingRequest struct {
+	Passengers    []GroupPassenger
+	LaunchpadID   string
+	DestinationID string
+	LaunchDate    Date `json:"Date"`
+}
+
+// GroupPassenger is either a known passenger, by PassengerID, or the passenger details.
+type GroupPassenger struct {
+	PassengerID string `json:",omitempty"`
+	FirstName   string
+	LastName    string
+	Gender      string
+	Birthday    Date
+}
+
+func (o *GroupBookingRequest) Validate() error {
+	if len(o.Passengers) == 0 || len(o.Passengers) > MaxGroupSize {
+		return fmt.Errorf("Passengers must be between 1 and %d", MaxGroupSize)
+	}
+	for i := range o.Passengers {
+		req := o.bookingRequest(i)
+		if err := req.Validate(); err != nil {
+			return fmt.Errorf("passenger %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// bookingRequest returns the single booking request of the i-th passenger.
+func (o *GroupBookingRequest) bookingRequest(i int) BookingRequest {
+	p := o.Passengers[i]
+	return BookingRequest{
+		PassengerID:   p.PassengerID,
+		FirstName:     p.FirstName,
+		LastName:      p.LastName,
+		Gender:        p.Gender,
+		Birthday:      p.Birthday,
+		LaunchpadID:   o.LaunchpadID,
+		DestinationID: o.DestinationID,
+		LaunchDate:    o.LaunchDate,
+	}
+}
+
+// RebookRequest moves a booking to another flight. Empty fields keep the value
+// of the current flight of the booking. The booking keeps the price it was booked at.
+type RebookRequest struct {
+	LaunchpadID   string `json:",omitempty"`
+	DestinationID string `json:",omitempty"`
+	LaunchDate    Date   `json:"Date"`
+}
+
+func (o *RebookRequest) Validate() error {
+	if o.LaunchpadID == "" && o.DestinationID == "" && o.LaunchDate.IsZero() {
+		return errors.New("nothing to change")
+	}
+	if !o.LaunchDate.IsZero() && o.LaunchDate.Before(time.Now()) {
+		return errors.New("Date is in the past")
+	}
+	if o.LaunchpadID != "" && len(o.LaunchpadID) != 24 {
 		return errors.New("launchPadID must have length 24")
 	}
+	if o.DestinationID != "" {
+		if _, err := uuid.Parse(o.DestinationID); err != nil {
+			return errors.New("invalid uuid for DestinationID")
+		}
+	}
+	return nil
+}
+
+// QuoteRequest asks for the price of a booking. The passenger is either
+// a known passenger, by PassengerID, or their Birthday.
+type QuoteRequest struct {
+	PassengerID   string
+	Birthday      Date
+	LaunchpadID   string
+	DestinationID string
+	LaunchDate    Date
+}
+
+func (o *QuoteRequest) Validate() error {
+	if o.PassengerID != "" {
+		if _, err := uuid.Parse(o.PassengerID); err != nil {
+			return errors.New("invalid uuid for passenger")
+		}
+	} else if o.Birthday.IsZero() {
+		return errors.New("empty birthday")
+	} else if o.Birthday.After(time.Now()) {
+		return errors.New("birthday is in the future")
+	}
+	if o.LaunchDate.IsZero() {
+		return errors.New("date is empty")
+	}
+	if o.LaunchDate.Before(time.Now()) {
+		return errors.New("date is in the past")
+	}
+	if len(o.LaunchpadID) != 24 {
+		return errors.New("launchpad must have length 24")
+	}
 	if _, err := uuid.Parse(o.DestinationID); err != nil {
-		return errors.New("invalid uuid for DestinationID")
+		return errors.New("invalid uuid for destination")
 	}
+	return nil
+}
+
+// HoldRequest reserves a seat for Minutes, DefaultHoldMinutes when empty,
+// until the hold is confirmed into an active booking.
+type HoldRequest struct {
+	BookingRequest
+	Minutes int `json:",omitempty"`
+}
 
+func (o *HoldRequest) Validate() error {
+	if o.Minutes < 0 || o.Minutes > MaxHoldMinutes {
+		return fmt.Errorf("Minutes must be between 0 (default) and %d", MaxHoldMinutes)
+	}
+	return o.BookingRequest.Validate()
+}
+
+// CapacityPolicy decides the number of seats of new flights.
+// A launchpad specific capacity wins over a destination specific one.
+type CapacityPolicy struct {
+	Default       int
+	ByLaunchpad   map[string]int
+	ByDestination map[string]int
+}
+
+func (o CapacityPolicy) For(launchpadID, destinationID string) int {
+	if c, ok := o.ByLaunchpad[launchpadID]; ok && c > 0 {
+		return c
+	}
+	if c, ok := o.ByDestination[destinationID]; ok && c > 0 {
+		return c
+	}
+	if o.Default > 0 {
+		return o.Default
+	}
+	return DefaultFlightCapacity
+}
+
+type CancelBookingRequest struct {
+	Reason string
+}
+
+func (o *CancelBookingRequest) Validate() error {
+	if len(o.Reason) > 255 {
+		return errors.New("Reason must be less than 255 chars")
+	}
 	return nil
 }
 
@@ -102,37 +284,115 @@ type BookingResponse struct {
 	entity.Booking
 }
 
-type AllBookingsResponse struct {
+type GroupBookingResponse struct {
 	Bookings []BookingResponse `json:"bookings"`
-	Limit    int               `json:"limit"`
-	Cursor   string            `json:"cursor"`
 }
 
+type BookingHistoryResponse struct {
+	Events []entity.BookingEvent `json:"events"`
+}
+
+type QuoteResponse struct {
+	pricing.Quote
+}
+
+type PassengerResponse struct {
+	entity.User
+}
+
+type WaitlistResponse struct {
+	entity.WaitlistEntry
+}
+
+// AllBookingsResponse is a page of bookings. Cursor fetches the next page, HasMore tells
+// whether it has bookings, and PrevCursor, set unless this is the first page, the previous one.
+// Total is only set when asked for.
+type AllBookingsResponse struct {
+	Bookings       []BookingResponse `json:"bookings"`
+	Limit          int               `json:"limit"`
+	Cursor         string            `json:"cursor"`
+	PrevCursor     string            `json:"prev_cursor,omitempty"`
+	HasMore        bool              `json:"has_more"`
+	Total          *int64            `json:"total,omitempty"`
+	TotalEstimated bool              `json:"total_estimated,omitempty"`
+}
+
+// GetBookingsReq lists bookings. Sort is created_at, the default, or launch_date,
+// prefixed by - for the descending order. Cursor is the previous or next cursor of a page
+// listed with the same filters and sort.
 type GetBookingsReq struct {
-	Limit int
-	Uuid  string
-	Ts    time.Time
+	Limit         int
+	Cursor        string
+	Status        string
+	DestinationID string
+	LaunchpadID   string
+	LaunchFrom    time.Time
+	LaunchTo      time.Time
+	Passenger     string
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	Sort          string
+	// Total is TotalExact or TotalEstimate to count the bookings matching the filters.
+	Total string
 }
 
-func decodeCursor(encoded string) (ans time.Time, uuid string, err error) {
-	b, err := base64.StdEncoding.DecodeString(encoded)
-	if err != nil {
-		return
+func (o *GetBookingsReq) Validate() error {
+	switch o.Status {
+	case "", entity.BookingStatusActive, entity.BookingStatusCancelled, entity.BookingStatusHeld,
+		entity.BookingStatusExpired, entity.BookingStatusPendingPayment:
+	default:
+		return errors.New("invalid status")
 	}
-	arr := strings.Split(string(b), ",")
-	if len(arr) != 2 {
-		err = errors.New("invalid cursor")
-		return
+	if o.DestinationID != "" {
+		if _, err := uuid.Parse(o.DestinationID); err != nil {
+			return errors.New("invalid uuid for destination")
+		}
 	}
-	ans, err = time.Parse(time.RFC3339Nano, arr[0])
-	if err != nil {
-		return
+	if o.LaunchpadID != "" && len(o.LaunchpadID) != 24 {
+		return errors.New("launchpad must have length 24")
+	}
+	if !o.LaunchFrom.IsZero() && !o.LaunchTo.IsZero() && o.LaunchTo.Before(o.LaunchFrom) {
+		return errors.New("to is before from")
+	}
+	if !o.CreatedFrom.IsZero() && !o.CreatedTo.IsZero() && o.CreatedTo.Before(o.CreatedFrom) {
+		return errors.New("created_to is before created_from")
+	}
+	switch strings.TrimPrefix(o.Sort, "-") {
+	case "", entity.BookingSortCreatedAt, entity.BookingSortLaunchDate:
+	default:
+		return errors.New("invalid sort")
+	}
+	if o.Total != "" && o.Total != TotalExact && o.Total != TotalEstimate {
+		return errors.New("total must be exact or estimate")
 	}
-	uuid = arr[1]
-	return
+	return nil
+}
+
+// filter builds the store filter, created dates are whole days so CreatedTo
+// is moved to the start of the next day.
+func (o *GetBookingsReq) filter() entity.BookingFilter {
+	f := entity.BookingFilter{
+		Status:        o.Status,
+		DestinationID: o.DestinationID,
+		LaunchpadID:   o.LaunchpadID,
+		LaunchFrom:    o.LaunchFrom,
+		LaunchTo:      o.LaunchTo,
+		PassengerName: o.Passenger,
+		CreatedFrom:   o.CreatedFrom,
+		SortBy:        strings.TrimPrefix(o.Sort, "-"),
+		Desc:          strings.HasPrefix(o.Sort, "-"),
+		Limit:         o.Limit,
+	}
+	if !o.CreatedTo.IsZero() {
+		f.CreatedTo = o.CreatedTo.AddDate(0, 0, 1)
+	}
+	return f
 }
 
-func encodeCursor(t time.Time, uuid string) string {
-	key := fmt.Sprintf("%s,%s", t.Format(time.RFC3339Nano), uuid)
-	return base64.StdEncoding.EncodeToString([]byte(key))
+// sortKey returns the value of the booking the list is sorted on.
+func (o *GetBookingsReq) sortKey(b entity.Booking) time.Time {
+	if strings.TrimPrefix(o.Sort, "-") == entity.BookingSortLaunchDate {
+		return b.Flight.Date
+	}
+	return b.CreatedAt
 }
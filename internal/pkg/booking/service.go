@@ -2,123 +2,830 @@ package booking
 
 import (
 	"context"
+	"errors"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
 
 	"spacetrouble/internal/pkg/entity"
+	"spacetrouble/internal/pkg/pricing"
+	"spacetrouble/internal/pkg/spacex"
 )
 
 type BookingService interface {
 	MakeBooking(ctx context.Context, req BookingRequest) (BookingResponse, error)
+	ImportBooking(ctx context.Context, req ImportRequest) (BookingResponse, error)
+	MakeGroupBooking(ctx context.Context, req GroupBookingRequest) (GroupBookingResponse, error)
+	HoldBooking(ctx context.Context, req HoldRequest) (BookingResponse, error)
+	ConfirmBooking(ctx context.Context, id string) (BookingResponse, error)
+	ExpireHolds(ctx context.Context) (int, error)
+	RetryRefunds(ctx context.Context) (int, error)
 	AllBookings(ctx context.Context, req GetBookingsReq) (AllBookingsResponse, error)
+	CancelBooking(ctx context.Context, id string, req CancelBookingRequest) (BookingResponse, error)
+	RebookBooking(ctx context.Context, id string, req RebookRequest) (BookingResponse, error)
+	GetBooking(ctx context.Context, id string) (BookingResponse, error)
+	BookingHistory(ctx context.Context, id string) (BookingHistoryResponse, error)
+	Quote(ctx context.Context, req QuoteRequest) (QuoteResponse, error)
+	JoinWaitlist(ctx context.Context, req BookingRequest) (WaitlistResponse, error)
+	GetWaitlistEntry(ctx context.Context, id string) (WaitlistResponse, error)
+	GetPassenger(ctx context.Context, id string) (PassengerResponse, error)
+	PassengerBookings(ctx context.Context, id string, req GetBookingsReq) (AllBookingsResponse, error)
+	ExportBookings(ctx context.Context, req GetBookingsReq, fn func(BookingResponse) error) error
 }
 
+// WaitlistActor is recorded in the booking history for the bookings made from the waitlist.
+const WaitlistActor = "waitlist"
+
 type SpaceX interface {
 	IsLaunchpadAvailable(ctx context.Context, launchpadID string, ts time.Time) (bool, error)
 }
 
+// PaymentProvider charges passengers. Amounts are in cents.
+type PaymentProvider interface {
+	// Capture charges the booking and returns the reference of the payment.
+	Capture(ctx context.Context, bookingID string, amount int64, currency string) (string, error)
+	// Refund gives back the amount of a capture and returns the reference of the refund.
+	Refund(ctx context.Context, captureRef string, amount int64) (string, error)
+}
+
 type bookingSrv struct {
-	store  entity.Store
-	spacex SpaceX
+	store    entity.Store
+	spacex   SpaceX
+	capacity CapacityPolicy
+	pricing  pricing.Policy
+	payments PaymentProvider
+	// paymentWindow is how long a booking waits for its payment before it gives its seat back
+	paymentWindow time.Duration
+	// cursorSecret signs the pagination cursors
+	cursorSecret []byte
+}
+
+// Option customizes the booking service.
+type Option func(*bookingSrv)
+
+func WithCapacityPolicy(p CapacityPolicy) Option {
+	return func(o *bookingSrv) {
+		o.capacity = p
+	}
+}
+
+func WithPricing(p pricing.Policy) Option {
+	return func(o *bookingSrv) {
+		o.pricing = p
+	}
 }
 
-func NewBookingService(store entity.Store, spacex SpaceX) *bookingSrv {
+// WithPaymentWindow sets how long the bookings have to be paid, DefaultPaymentWindow by default.
+func WithPaymentWindow(d time.Duration) Option {
+	return func(o *bookingSrv) {
+		o.paymentWindow = d
+	}
+}
+
+// WithCursorSecret sets the key signing the pagination cursors, so that
+// cursors stay valid across restarts and instances.
+func WithCursorSecret(secret []byte) Option {
+	return func(o *bookingSrv) {
+		o.cursorSecret = secret
+	}
+}
+
+func NewBookingService(store entity.Store, spacex SpaceX, payments PaymentProvider, opts ...Option) *bookingSrv {
 	ans := bookingSrv{
-		store:  store,
-		spacex: spacex,
+		store:         store,
+		spacex:        spacex,
+		capacity:      CapacityPolicy{Default: DefaultFlightCapacity},
+		pricing:       pricing.Policy{BaseFare: pricing.DefaultBaseFare},
+		payments:      payments,
+		paymentWindow: DefaultPaymentWindow,
+	}
+	for _, opt := range opts {
+		opt(&ans)
+	}
+	if len(ans.cursorSecret) == 0 {
+		ans.cursorSecret = newCursorSecret()
 	}
 	return &ans
 }
 
 func (o *bookingSrv) AllBookings(ctx context.Context, req GetBookingsReq) (AllBookingsResponse, error) {
+	return o.listBookings(ctx, "", req)
+}
+
+func (o *bookingSrv) GetPassenger(ctx context.Context, id string) (PassengerResponse, error) {
+	var ans PassengerResponse
+	if _, err := uuid.Parse(id); err != nil {
+		return ans, ErrInvalidUUID
+	}
+	user, err := o.store.GetUserById(ctx, id)
+	if errors.Is(err, entity.ErrNotFound) {
+		return ans, ErrPassengerNotFound
+	}
+	if err != nil {
+		return ans, err
+	}
+	ans.User = user
+	return ans, nil
+}
+
+// PassengerBookings returns every booking of the passenger, whatever its status.
+func (o *bookingSrv) PassengerBookings(ctx context.Context, id string, req GetBookingsReq) (AllBookingsResponse, error) {
+	if _, err := o.GetPassenger(ctx, id); err != nil {
+		return AllBookingsResponse{Bookings: make([]BookingResponse, 0), Limit: req.Limit}, err
+	}
+	return o.listBookings(ctx, id, req)
+}
+
+// ExportBookings calls fn with every booking matching the filters of the request,
+// its limit and cursor are ignored.
+func (o *bookingSrv) ExportBookings(ctx context.Context, req GetBookingsReq, fn func(BookingResponse) error) error {
+	return o.store.StreamBookings(ctx, req.filter(), func(b entity.Booking) error {
+		return fn(BookingResponse{Booking: b})
+	})
+}
+
+// listBookings fetches a page of bookings, of a passenger when userID is set.
+// The cursor must have been issued for the same filters. A previous page is fetched
+// in the reverse order and reversed back. One more booking than the limit is fetched
+// to tell whether there is another page in the direction of the fetch.
+func (o *bookingSrv) listBookings(ctx context.Context, userID string, req GetBookingsReq) (AllBookingsResponse, error) {
 	ans := AllBookingsResponse{
 		Bookings: make([]BookingResponse, 0),
 		Limit:    req.Limit,
 	}
-	bookings, err := o.store.AllBookingsPaginated(ctx, req.Ts, req.Uuid, req.Limit)
+	filter := req.filter()
+	filter.UserID = userID
+	filters := newCursorFilters(userID, req)
+	backward := false
+	if req.Cursor != "" {
+		c, err := decodeCursor(o.cursorSecret, req.Cursor)
+		if err != nil || c.Filters != filters {
+			return ans, ErrInvalidCursor
+		}
+		filter.AfterValue, filter.AfterID = c.Value, c.ID
+		if c.Direction == cursorPrev {
+			backward = true
+			filter.Desc = !filter.Desc
+		}
+	}
+	if req.Limit > 0 {
+		filter.Limit = req.Limit + 1
+	}
+	bookings, err := o.store.AllBookingsPaginated(ctx, filter)
 	if err != nil {
 		return ans, err
 	}
+	more := req.Limit > 0 && len(bookings) > req.Limit
+	if more {
+		bookings = bookings[:req.Limit]
+	}
+	// going back the next page is the one the cursor came from
+	ans.HasMore = more || backward
+	if backward {
+		for i, j := 0, len(bookings)-1; i < j; i, j = i+1, j-1 {
+			bookings[i], bookings[j] = bookings[j], bookings[i]
+		}
+	}
 	for i := range bookings {
 		ans.Bookings = append(ans.Bookings, BookingResponse{Booking: bookings[i]})
 	}
-	if len(ans.Bookings) > 0 {
-		ans.Cursor = encodeCursor(
-			ans.Bookings[len(ans.Bookings)-1].CreatedAt,
-			ans.Bookings[len(ans.Bookings)-1].ID.String(),
-		)
+	if len(bookings) > 0 {
+		first, last := bookings[0], bookings[len(bookings)-1]
+		ans.Cursor = encodeCursor(o.cursorSecret, cursor{
+			Direction: cursorNext,
+			Value:     req.sortKey(last),
+			ID:        last.ID.String(),
+			Filters:   filters,
+		})
+		if (!backward && req.Cursor != "") || (backward && more) {
+			ans.PrevCursor = encodeCursor(o.cursorSecret, cursor{
+				Direction: cursorPrev,
+				Value:     req.sortKey(first),
+				ID:        first.ID.String(),
+				Filters:   filters,
+			})
+		}
+	}
+	if req.Total != "" {
+		total, err := o.store.CountBookings(ctx, filter, req.Total == TotalExact)
+		if err != nil {
+			return ans, err
+		}
+		ans.Total = &total
+		ans.TotalEstimated = req.Total == TotalEstimate
 	}
 	return ans, nil
 }
 
 func (o *bookingSrv) MakeBooking(ctx context.Context, req BookingRequest) (BookingResponse, error) {
+	payBy := o.payBy()
+	return o.makeBooking(ctx, req, &payBy)
+}
+
+// ImportBooking books a row of a partner manifest following the same rules as MakeBooking.
+// The booking is pending payment until the PayBy of the row, or active right away without it
+// as the passenger paid the partner.
+func (o *bookingSrv) ImportBooking(ctx context.Context, req ImportRequest) (BookingResponse, error) {
+	return o.makeBooking(ctx, req.BookingRequest, req.PayBy)
+}
+
+// makeBooking books a seat pending payment until payBy, or active without payBy.
+func (o *bookingSrv) makeBooking(ctx context.Context, req BookingRequest, payBy *time.Time) (BookingResponse, error) {
 	var ans BookingResponse
+	user, flight, err := o.prepareBooking(ctx, req)
+	if err != nil {
+		return ans, err
+	}
+	quote, err := o.quote(ctx, flight, user.Birthday, 0)
+	if err != nil {
+		return ans, err
+	}
+	// we can now create the booking
+	newBooking, err := o.store.CreateBooking(ctx, user, flight, quote.Price, payBy)
+	if err != nil {
+		return ans, mapCreateBookingError(err)
+	}
+	ans.Booking = newBooking
+	return ans, nil
+}
+
+// MakeGroupBooking applies the booking rules once for the whole group
+// and books either every passenger or none of them.
+func (o *bookingSrv) MakeGroupBooking(ctx context.Context, req GroupBookingRequest) (GroupBookingResponse, error) {
+	ans := GroupBookingResponse{Bookings: make([]BookingResponse, 0, len(req.Passengers))}
 	destination, err := o.store.GetDestinationById(ctx, req.DestinationID)
 	if err != nil {
 		return ans, ErrMissingDestination
 	}
 
-	if err := o.islaunchpadUsed(ctx, req.LaunchpadID, destination.ID.String(), req.LaunchDate.Time); err != nil {
+	users := make([]entity.User, 0, len(req.Passengers))
+	for i := range req.Passengers {
+		user, err := o.passenger(ctx, req.bookingRequest(i))
+		if err != nil {
+			return ans, err
+		}
+		users = append(users, user)
+	}
+
+	flight, err := o.resolveFlight(ctx, req.LaunchpadID, destination, req.LaunchDate.Time)
+	if err != nil {
 		return ans, err
 	}
+	// every passenger is priced with the load of the seats of the group before theirs
+	prices := make([]int64, 0, len(users))
+	for i, u := range users {
+		quote, err := o.quote(ctx, flight, u.Birthday, i)
+		if err != nil {
+			return ans, err
+		}
+		prices = append(prices, quote.Price)
+	}
+	bookings, err := o.store.CreateGroupBooking(ctx, users, prices, flight, o.payBy())
+	if err != nil {
+		return ans, mapCreateBookingError(err)
+	}
+	for i := range bookings {
+		ans.Bookings = append(ans.Bookings, BookingResponse{Booking: bookings[i]})
+	}
+	return ans, nil
+}
 
-	flight, err := o.currentFlightLaunchPad(ctx, req.LaunchpadID, destination.ID.String(), req.LaunchDate.Time)
+// HoldBooking reserves a seat following the same rules as MakeBooking.
+// The hold expires after the requested minutes unless it is confirmed.
+func (o *bookingSrv) HoldBooking(ctx context.Context, req HoldRequest) (BookingResponse, error) {
+	var ans BookingResponse
+	user, flight, err := o.prepareBooking(ctx, req.BookingRequest)
+	if err != nil {
+		return ans, err
+	}
+	minutes := req.Minutes
+	if minutes == 0 {
+		minutes = DefaultHoldMinutes
+	}
+	quote, err := o.quote(ctx, flight, user.Birthday, 0)
 	if err != nil {
 		return ans, err
 	}
+	held, err := o.store.CreateHold(ctx, user, flight, quote.Price, time.Now().Add(time.Duration(minutes)*time.Minute))
+	if err != nil {
+		return ans, mapCreateBookingError(err)
+	}
+	ans.Booking = held
+	return ans, nil
+}
+
+// payBy is the payment deadline of a booking made now.
+func (o *bookingSrv) payBy() time.Time {
+	return time.Now().Add(o.paymentWindow)
+}
+
+// ConfirmBooking captures the payment of a held or pending payment booking and activates it.
+// When the booking cannot be activated after all the payment is refunded.
+func (o *bookingSrv) ConfirmBooking(ctx context.Context, id string) (BookingResponse, error) {
+	var ans BookingResponse
+	b, err := o.GetBooking(ctx, id)
+	if err != nil {
+		return ans, err
+	}
+	if !entity.CanTransitionBooking(b.Status, entity.BookingStatusActive) {
+		return ans, ErrBookingNotConfirmable
+	}
+	if b.ExpiresAt != nil && !b.ExpiresAt.After(time.Now()) {
+		return ans, ErrHoldExpired
+	}
+	ref, err := o.payments.Capture(ctx, id, b.Price, pricing.Currency)
+	if err != nil {
+		log.Printf("capturing payment of booking %s: %v", id, err)
+		return ans, ErrPaymentFailed
+	}
+	confirmed, err := o.store.ConfirmBooking(ctx, id, entity.Payment{
+		Amount:      b.Price,
+		Currency:    pricing.Currency,
+		ProviderRef: ref,
+	})
+	if err != nil {
+		// confirmed, cancelled or expired meanwhile
+		if _, rerr := o.payments.Refund(ctx, ref, b.Price); rerr != nil {
+			log.Printf("refunding payment %s of booking %s: %v", ref, id, rerr)
+			if rerr := o.deferRefund(ctx, b.ID, ref, b.Price, pricing.Currency); rerr != nil {
+				log.Printf("recording the pending refund of payment %s of booking %s: %v", ref, id, rerr)
+			}
+		}
+	}
+	switch {
+	case errors.Is(err, entity.ErrInvalidStatusTransition), errors.Is(err, entity.ErrAlreadyExists):
+		return ans, ErrBookingNotConfirmable
+	case errors.Is(err, entity.ErrHoldExpired):
+		return ans, ErrHoldExpired
+	case err != nil:
+		return ans, err
+	}
+	ans.Booking = confirmed
+	return ans, nil
+}
+
+// ExpireHolds releases the seats of the holds and the unpaid bookings that ran out and books
+// the waiting passengers on them. It returns the number of expired bookings.
+func (o *bookingSrv) ExpireHolds(ctx context.Context) (int, error) {
+	expired, err := o.store.ExpireHolds(ctx, time.Now())
+	for _, b := range expired {
+		if err := o.promoteWaitlist(ctx, b.Flight.LaunchpadID, b.Flight.Date); err != nil {
+			log.Printf("promoting waitlist for launchpad %s on %s: %v",
+				b.Flight.LaunchpadID, b.Flight.Date.Format(dateLayoutFmt), err)
+		}
+	}
+	return len(expired), err
+}
+
+// Quote prices a booking the same way MakeBooking does, without booking.
+// The booking rules are checked so that only bookable flights are quoted.
+func (o *bookingSrv) Quote(ctx context.Context, req QuoteRequest) (QuoteResponse, error) {
+	var ans QuoteResponse
+	destination, err := o.store.GetDestinationById(ctx, req.DestinationID)
+	if err != nil {
+		return ans, ErrMissingDestination
+	}
+	birthday := req.Birthday.Time
+	if req.PassengerID != "" {
+		user, err := o.passenger(ctx, BookingRequest{PassengerID: req.PassengerID})
+		if err != nil {
+			return ans, err
+		}
+		birthday = user.Birthday
+	}
+	flight, err := o.resolveFlight(ctx, req.LaunchpadID, destination, req.LaunchDate.Time)
+	if err != nil {
+		return ans, err
+	}
+	ans.Quote, err = o.quote(ctx, flight, birthday, 0)
+	return ans, err
+}
+
+// quote prices a seat on the flight for a passenger born on birthday. ahead is the number of seats
+// booked before this one by the same request, e.g. the previous passengers of a group, which count in the load.
+func (o *bookingSrv) quote(ctx context.Context, flight entity.Flight, birthday time.Time, ahead int) (pricing.Quote, error) {
+	taken, err := o.seatsTaken(ctx, flight)
+	if err != nil {
+		return pricing.Quote{}, err
+	}
+	return o.pricing.Quote(pricingInput(flight, taken+ahead, birthday), time.Now()), nil
+}
+
+func (o *bookingSrv) seatsTaken(ctx context.Context, flight entity.Flight) (int, error) {
+	if flight.IsIDEmpty() {
+		return 0, nil
+	}
+	return o.store.CountSeatsTaken(ctx, flight.ID.String())
+}
+
+func pricingInput(flight entity.Flight, seatsTaken int, birthday time.Time) pricing.Input {
+	return pricing.Input{
+		DestinationID: flight.Destination.ID.String(),
+		LaunchDate:    flight.Date,
+		Birthday:      birthday,
+		SeatsTaken:    seatsTaken,
+		Capacity:      flight.Capacity,
+	}
+}
+
+// prepareBooking returns the passenger and the flight to book them on.
+func (o *bookingSrv) prepareBooking(ctx context.Context, req BookingRequest) (entity.User, entity.Flight, error) {
+	destination, err := o.store.GetDestinationById(ctx, req.DestinationID)
+	if err != nil {
+		return entity.User{}, entity.Flight{}, ErrMissingDestination
+	}
+
+	user, err := o.passenger(ctx, req)
+	if err != nil {
+		return user, entity.Flight{}, err
+	}
+
+	flight, err := o.resolveFlight(ctx, req.LaunchpadID, destination, req.LaunchDate.Time)
+	return user, flight, err
+}
+
+// passenger returns the known passenger when the request has a PassengerID.
+// Otherwise the passenger is matched on name, gender and birthday when booking.
+func (o *bookingSrv) passenger(ctx context.Context, req BookingRequest) (entity.User, error) {
+	if req.PassengerID == "" {
+		user := entity.User{
+			FirstName: req.FirstName,
+			LastName:  req.LastName,
+			Birthday:  req.Birthday.Time,
+		}
+		if len(req.Gender) > 0 {
+			user.Gender = string(req.Gender[0])
+		}
+		return user, nil
+	}
+	if _, err := uuid.Parse(req.PassengerID); err != nil {
+		return entity.User{}, ErrInvalidUUID
+	}
+	user, err := o.store.GetUserById(ctx, req.PassengerID)
+	if errors.Is(err, entity.ErrNotFound) {
+		return user, ErrPassengerNotFound
+	}
+	return user, err
+}
+
+// resolveFlight applies the booking rules and returns the flight to book on.
+// When there is no flight yet the returned flight has an empty ID and
+// will be created along with the booking.
+func (o *bookingSrv) resolveFlight(ctx context.Context, launchpadId string, destination entity.Destination, date time.Time) (entity.Flight, error) {
+	return o.resolveFlightReleasing(ctx, launchpadId, destination, date, uuid.Nil)
+}
+
+// resolveFlightReleasing is resolveFlight for a booking whose move releases the flight released,
+// which then no longer holds its launchpad and week.
+func (o *bookingSrv) resolveFlightReleasing(ctx context.Context, launchpadId string, destination entity.Destination,
+	date time.Time, released uuid.UUID) (entity.Flight, error) {
+	if err := o.islaunchpadUsed(ctx, launchpadId, destination.ID.String(), date, released); err != nil {
+		return entity.Flight{}, err
+	}
+
+	flight, err := o.currentFlightLaunchPad(ctx, launchpadId, destination.ID.String(), date)
+	if err != nil {
+		return flight, err
+	}
 
 	// When we don't already have a flight.ID then we check the availability of spaceX
 	if flight.IsIDEmpty() {
 
 		// before that we check that we can make a booking for the destination for this week.
 		// if there is already on from the same launchpad abort
-		err = o.sameDestinationLaunchPad(ctx, req.LaunchpadID, destination.ID.String(), req.LaunchDate.Time)
+		err = o.sameDestinationLaunchPad(ctx, launchpadId, destination.ID.String(), date, released)
 		if err != nil {
-			return ans, err
+			return flight, err
 		}
-		flight, err = o.createFlightSpaceX(ctx, req.LaunchpadID, destination, req.LaunchDate.Time)
+		flight, err = o.createFlightSpaceX(ctx, launchpadId, destination, date)
 		if err != nil {
-			return ans, err
+			return flight, err
 		}
 	}
+	return flight, nil
+}
 
-	user := entity.User{
-		ID:        uuid.New(),
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Gender:    string(req.Gender[0]),
-		Birthday:  req.Birthday.Time,
+func mapCreateBookingError(err error) error {
+	switch {
+	case errors.Is(err, entity.ErrFlightNotScheduled):
+		// the flight got cancelled while we were booking
+		return ErrLaunchPadUnavailable
+	case errors.Is(err, entity.ErrFlightFull):
+		return ErrFlightFull
+	case errors.Is(err, entity.ErrAlreadyBooked):
+		return ErrAlreadyBooked
+	default:
+		return err
+	}
+}
+
+func (o *bookingSrv) GetBooking(ctx context.Context, id string) (BookingResponse, error) {
+	var ans BookingResponse
+	if _, err := uuid.Parse(id); err != nil {
+		return ans, ErrInvalidUUID
+	}
+	b, err := o.store.GetBookingById(ctx, id)
+	if errors.Is(err, entity.ErrNotFound) {
+		return ans, ErrBookingNotFound
 	}
-	// we can now create the booking
-	newBooking, err := o.store.CreateBooking(ctx, user, flight)
 	if err != nil {
 		return ans, err
 	}
-	ans.Booking = newBooking
+	ans.Booking = b
+	return ans, nil
+}
+
+// BookingHistory returns every change of the booking, oldest first.
+func (o *bookingSrv) BookingHistory(ctx context.Context, id string) (BookingHistoryResponse, error) {
+	ans := BookingHistoryResponse{Events: make([]entity.BookingEvent, 0)}
+	if _, err := o.GetBooking(ctx, id); err != nil {
+		return ans, err
+	}
+	events, err := o.store.BookingEvents(ctx, id)
+	if err != nil {
+		return ans, err
+	}
+	ans.Events = append(ans.Events, events...)
+	return ans, nil
+}
+
+// CancelBooking moves an active, held or pending payment booking to cancelled, refunding it when paid. When it was the last seat
+// taken on its flight the flight is cancelled too and the launchpad is freed for that date.
+func (o *bookingSrv) CancelBooking(ctx context.Context, id string, req CancelBookingRequest) (BookingResponse, error) {
+	var ans BookingResponse
+	if _, err := uuid.Parse(id); err != nil {
+		return ans, ErrInvalidUUID
+	}
+	cancelled, err := o.store.CancelBooking(ctx, id, req.Reason)
+	switch {
+	case errors.Is(err, entity.ErrNotFound):
+		return ans, ErrBookingNotFound
+	case errors.Is(err, entity.ErrInvalidStatusTransition):
+		return ans, ErrBookingNotCancelable
+	case err != nil:
+		return ans, err
+	}
+	ans.Booking = cancelled
+
+	// the cancellation is done, failing to refund or promote does not undo it
+	if err := o.refund(ctx, cancelled); err != nil {
+		log.Printf("refunding booking %s: %v", cancelled.ID, err)
+	}
+	if err := o.promoteWaitlist(ctx, cancelled.Flight.LaunchpadID, cancelled.Flight.Date); err != nil {
+		log.Printf("promoting waitlist for launchpad %s on %s: %v",
+			cancelled.Flight.LaunchpadID, cancelled.Flight.Date.Format(dateLayoutFmt), err)
+	}
 	return ans, nil
 }
 
+// RebookBooking moves a booking to another date, launchpad or destination following the booking rules.
+// The booking keeps its ID, status and price, it is not quoted again. The seat it frees goes to the waitlist.
+func (o *bookingSrv) RebookBooking(ctx context.Context, id string, req RebookRequest) (BookingResponse, error) {
+	var ans BookingResponse
+	current, err := o.GetBooking(ctx, id)
+	if err != nil {
+		return ans, err
+	}
+	if !entity.IsOccupyingBookingStatus(current.Status) {
+		return ans, ErrBookingNotModifiable
+	}
+	launchpadId, destinationId, date := current.Flight.LaunchpadID, current.Flight.Destination.ID.String(), current.Flight.Date
+	if req.LaunchpadID != "" {
+		launchpadId = req.LaunchpadID
+	}
+	if req.DestinationID != "" {
+		destinationId = req.DestinationID
+	}
+	if !req.LaunchDate.IsZero() {
+		date = req.LaunchDate.Time
+	}
+	if launchpadId == current.Flight.LaunchpadID && destinationId == current.Flight.Destination.ID.String() &&
+		date.Equal(current.Flight.Date) {
+		return current, nil
+	}
+
+	destination, err := o.store.GetDestinationById(ctx, destinationId)
+	if err != nil {
+		return ans, ErrMissingDestination
+	}
+	// the current flight gives up its launchpad and week when the booking is its only seat
+	released := uuid.Nil
+	taken, err := o.seatsTaken(ctx, current.Flight)
+	if err != nil {
+		return ans, err
+	}
+	if taken == 1 {
+		released = current.Flight.ID
+	}
+	flight, err := o.resolveFlightReleasing(ctx, launchpadId, destination, date, released)
+	if err != nil {
+		return ans, err
+	}
+	rebooked, err := o.store.RebookBooking(ctx, id, flight)
+	switch {
+	case errors.Is(err, entity.ErrNotFound):
+		return ans, ErrBookingNotFound
+	case errors.Is(err, entity.ErrInvalidStatusTransition):
+		return ans, ErrBookingNotModifiable
+	case err != nil:
+		return ans, mapCreateBookingError(err)
+	}
+	ans.Booking = rebooked
+
+	// the rebooking is done, failing to promote does not undo it
+	if err := o.promoteWaitlist(ctx, current.Flight.LaunchpadID, current.Flight.Date); err != nil {
+		log.Printf("promoting waitlist for launchpad %s on %s: %v",
+			current.Flight.LaunchpadID, current.Flight.Date.Format(dateLayoutFmt), err)
+	}
+	return ans, nil
+}
+
+// refund gives the passenger their money back when the booking was paid
+// and records the refund.
+func (o *bookingSrv) refund(ctx context.Context, b entity.Booking) error {
+	capture, err := o.store.CapturedPayment(ctx, b.ID.String())
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ref, err := o.payments.Refund(ctx, capture.ProviderRef, capture.Amount)
+	if err != nil {
+		if derr := o.deferRefund(ctx, b.ID, capture.ProviderRef, capture.Amount, capture.Currency); derr != nil {
+			log.Printf("recording the pending refund of booking %s: %v", b.ID, derr)
+		}
+		return err
+	}
+	_, err = o.store.CreatePayment(ctx, entity.Payment{
+		BookingID:   b.ID,
+		Kind:        entity.PaymentKindRefund,
+		Amount:      capture.Amount,
+		Currency:    capture.Currency,
+		ProviderRef: ref,
+	})
+	return err
+}
+
+// deferRefund records a refund of the capture the payment provider failed, for RetryRefunds.
+func (o *bookingSrv) deferRefund(ctx context.Context, bookingId uuid.UUID, captureRef string, amount int64, currency string) error {
+	_, err := o.store.CreatePayment(ctx, entity.Payment{
+		BookingID:   bookingId,
+		Kind:        entity.PaymentKindRefundPending,
+		Amount:      amount,
+		Currency:    currency,
+		ProviderRef: captureRef,
+	})
+	return err
+}
+
+// RetryRefunds refunds again the payments whose refund failed and returns how many succeeded.
+// A refund failing again stays pending for the next retry.
+func (o *bookingSrv) RetryRefunds(ctx context.Context) (int, error) {
+	pending, err := o.store.PendingRefunds(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, p := range pending {
+		ref, err := o.payments.Refund(ctx, p.ProviderRef, p.Amount)
+		if err != nil {
+			log.Printf("refunding payment %s of booking %s: %v", p.ProviderRef, p.BookingID, err)
+			continue
+		}
+		if _, err := o.store.ResolvePendingRefund(ctx, p.ID, ref); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// JoinWaitlist queues the passenger for a flight that is full or whose launchpad is unavailable.
+// The passenger is booked automatically when a cancellation frees a seat.
+func (o *bookingSrv) JoinWaitlist(ctx context.Context, req BookingRequest) (WaitlistResponse, error) {
+	var ans WaitlistResponse
+	destination, err := o.store.GetDestinationById(ctx, req.DestinationID)
+	if err != nil {
+		return ans, ErrMissingDestination
+	}
+	user, err := o.passenger(ctx, req)
+	if err != nil {
+		return ans, err
+	}
+	// a passenger on the flight already would only block the queue once promoted
+	booked, err := o.store.IsBookedOn(ctx, user, req.LaunchpadID, req.LaunchDate.Time)
+	if err != nil {
+		return ans, err
+	}
+	if booked {
+		return ans, ErrAlreadyBooked
+	}
+	entry, err := o.store.CreateWaitlistEntry(ctx, entity.WaitlistEntry{
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Gender:      user.Gender,
+		Birthday:    user.Birthday,
+		LaunchpadID: req.LaunchpadID,
+		Destination: destination,
+		Date:        req.LaunchDate.Time,
+	})
+	if errors.Is(err, entity.ErrAlreadyExists) {
+		return ans, ErrAlreadyWaiting
+	}
+	if err != nil {
+		return ans, err
+	}
+	ans.WaitlistEntry = entry
+	return ans, nil
+}
+
+func (o *bookingSrv) GetWaitlistEntry(ctx context.Context, id string) (WaitlistResponse, error) {
+	var ans WaitlistResponse
+	if _, err := uuid.Parse(id); err != nil {
+		return ans, ErrInvalidUUID
+	}
+	entry, err := o.store.GetWaitlistEntryById(ctx, id)
+	if errors.Is(err, entity.ErrNotFound) {
+		return ans, ErrWaitlistEntryNotFound
+	}
+	if err != nil {
+		return ans, err
+	}
+	ans.WaitlistEntry = entry
+	return ans, nil
+}
+
+// promoteWaitlist books the waiting passengers of the launchpad and date, first come first served,
+// as long as the booking rules allow it. Passengers that still cannot be booked keep waiting.
+// The bookings are active right away, the passengers are not around to pay within the payment window.
+func (o *bookingSrv) promoteWaitlist(ctx context.Context, launchpadId string, date time.Time) error {
+	ctx = entity.WithActor(ctx, WaitlistActor)
+	entries, err := o.store.WaitingEntries(ctx, launchpadId, date)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		flight, err := o.resolveFlight(ctx, e.LaunchpadID, e.Destination, e.Date)
+		if CanWaitlist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		user := entity.User{
+			FirstName: e.FirstName,
+			LastName:  e.LastName,
+			Gender:    e.Gender,
+			Birthday:  e.Birthday,
+		}
+		quote, err := o.quote(ctx, flight, user.Birthday, 0)
+		if err != nil {
+			return err
+		}
+		_, err = o.store.PromoteWaitlistEntry(ctx, e.ID.String(), user, flight, quote.Price, nil)
+		err = mapCreateBookingError(err)
+		if CanWaitlist(err) || errors.Is(err, entity.ErrInvalidStatusTransition) {
+			// no seat for this one or promoted concurrently
+			continue
+		}
+		if err == ErrAlreadyBooked {
+			// booked on the flight since they joined, the entry must not block the ones behind it
+			if err := o.store.MarkWaitlistEntryStale(ctx, e.ID.String()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // we forbid booking from a launchpad that it's already used
-func (o *bookingSrv) islaunchpadUsed(ctx context.Context, launchpadId string, destinationId string, date time.Time) error {
+func (o *bookingSrv) islaunchpadUsed(ctx context.Context, launchpadId string, destinationId string, date time.Time,
+	released uuid.UUID) error {
 	flights, err := o.store.SelectFlights(
 		ctx,
 		map[string]interface{}{
 			"launchpad_id": launchpadId,
 			"launch_date":  date,
+			"status":       entity.FlightStatusScheduled,
 		},
 	)
 	if err != nil {
 		return err
 	}
-	if len(flights) > 0 && flights[0].Destination.ID.String() != destinationId {
+	if len(flights) > 0 && flights[0].ID != released && flights[0].Destination.ID.String() != destinationId {
 		return ErrLaunchPadUnavailable
 	}
 
 	return nil
 }
 
-// We search in our database if we have a flight with active booking
+// We search in our database if we have a flight with active or held bookings
 // for the launchpad destination and launch date.
 func (o *bookingSrv) currentFlightLaunchPad(ctx context.Context, launchpadId, destinationId string, date time.Time) (flight entity.Flight, err error) {
 	var flights []entity.Flight
@@ -128,7 +835,8 @@ func (o *bookingSrv) currentFlightLaunchPad(ctx context.Context, launchpadId, de
 			"launchpad_id":    launchpadId,
 			"destination_id":  destinationId,
 			"launch_date":     date,
-			"bookings.status": entity.BookingStatusActive,
+			"status":          entity.FlightStatusScheduled,
+			"bookings.status": entity.OccupyingBookingStatuses,
 		},
 	)
 	if err != nil {
@@ -140,8 +848,9 @@ func (o *bookingSrv) currentFlightLaunchPad(ctx context.Context, launchpadId, de
 	return
 }
 
-func (o *bookingSrv) sameDestinationLaunchPad(ctx context.Context, launchpadId, destinationId string, date time.Time) error {
-	ok, err := o.store.GetLaunchPadWeekAvailability(ctx, launchpadId, destinationId, date)
+func (o *bookingSrv) sameDestinationLaunchPad(ctx context.Context, launchpadId, destinationId string, date time.Time,
+	released uuid.UUID) error {
+	ok, err := o.store.GetLaunchPadWeekAvailability(ctx, launchpadId, destinationId, date, released)
 	if err != nil {
 		return err
 	}
@@ -155,6 +864,10 @@ func (o *bookingSrv) createFlightSpaceX(ctx context.Context, launchpadId string,
 	date time.Time) (flight entity.Flight, err error) {
 	var isAvailable bool
 	isAvailable, err = o.spacex.IsLaunchpadAvailable(ctx, launchpadId, date)
+	if errors.Is(err, spacex.ErrCircuitOpen) || errors.Is(err, spacex.ErrScheduleStale) {
+		err = ErrSpaceXUnavailable
+		return
+	}
 	if err != nil {
 		return
 	}
@@ -166,6 +879,7 @@ func (o *bookingSrv) createFlightSpaceX(ctx context.Context, launchpadId string,
 		LaunchpadID: launchpadId,
 		Destination: dst,
 		Date:        date,
+		Capacity:    o.capacity.For(launchpadId, dst.ID.String()),
 	}
 	return
 }
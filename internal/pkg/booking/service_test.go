@@ -12,6 +12,8 @@ import (
 	"github.com/jackc/pgx/v4/pgxpool"
 
 	"spacetrouble/internal/pkg/entity"
+	"spacetrouble/internal/pkg/payment"
+	"spacetrouble/internal/pkg/pricing"
 
 	"spacetrouble/internal/pkg/data/postgres"
 
@@ -36,6 +38,19 @@ func (o *SpaceXMockError) IsLaunchpadAvailable(ctx context.Context, launchpadId
 	return false, errors.New("spaceX api error")
 }
 
+// PaymentMockRefundError refunds nothing while failRefunds is set.
+type PaymentMockRefundError struct {
+	*payment.FakeProvider
+	failRefunds bool
+}
+
+func (o *PaymentMockRefundError) Refund(ctx context.Context, captureRef string, amount int64) (string, error) {
+	if o.failRefunds {
+		return "", errors.New("payment provider error")
+	}
+	return o.FakeProvider.Refund(ctx, captureRef, amount)
+}
+
 func genLaunchId() string {
 	return strings.Replace(uuid.New().String(), "-", "", -1)[:24]
 }
@@ -70,11 +85,9 @@ func createDestinations(store entity.Store) ([]entity.Destination, error) {
 	return store.GetAllDestinations(context.Background())
 }
 
+// cleanDatabase drops the database of the test.
 func cleanDatabase(db *pgxpool.Pool) {
-	_, err := db.Exec(context.Background(),
-		"truncate users cascade;truncate flights cascade; truncate bookings;",
-	)
-	if err != nil {
+	if err := testutils.DropTestDb(db); err != nil {
 		panic(err)
 	}
 }
@@ -103,7 +116,7 @@ func TestMakeBookingNewAndAvailable(t *testing.T) {
 	}
 
 	spaceClient := &SpaceXMockAvailable{}
-	srv := NewBookingService(store, spaceClient)
+	srv := NewBookingService(store, spaceClient, payment.NewFakeProvider())
 
 	birthday, _ := time.Parse(dateLayoutFmt, "13/11/1923")
 	launchDate, _ := time.Parse(dateLayoutFmt, "06/04/2021")
@@ -156,7 +169,7 @@ func TestMakeBookingMissingDestination(t *testing.T) {
 	}
 
 	spaceClient := &SpaceXMockAvailable{}
-	srv := NewBookingService(store, spaceClient)
+	srv := NewBookingService(store, spaceClient, payment.NewFakeProvider())
 
 	birthday, _ := time.Parse(dateLayoutFmt, "13/11/1923")
 	launchDate, _ := time.Parse(dateLayoutFmt, "06/04/2021")
@@ -193,7 +206,7 @@ func TestMakeBookingWhenThereAreAlreadyBookingsForFlight(t *testing.T) {
 	}
 
 	spaceClient := &SpaceXMockAvailable{}
-	srv := NewBookingService(store, spaceClient)
+	srv := NewBookingService(store, spaceClient, payment.NewFakeProvider())
 
 	birthday, _ := time.Parse(dateLayoutFmt, "13/11/1923")
 	launchDate, _ := time.Parse(dateLayoutFmt, "06/04/2021")
@@ -248,7 +261,7 @@ func TestMakeBookingWhenLaunchpadHasOtherBookingSameDate(t *testing.T) {
 	}
 
 	spaceClient := &SpaceXMockAvailable{}
-	srv := NewBookingService(store, spaceClient)
+	srv := NewBookingService(store, spaceClient, payment.NewFakeProvider())
 
 	birthday, _ := time.Parse(dateLayoutFmt, "13/11/1923")
 	launchDate, _ := time.Parse(dateLayoutFmt, "06/04/2021")
@@ -312,7 +325,7 @@ func TestMakeBookingFromSameLaunchpadToSameDestinationInSameWeek(t *testing.T) {
 	}
 
 	spaceClient := &SpaceXMockAvailable{}
-	srv := NewBookingService(store, spaceClient)
+	srv := NewBookingService(store, spaceClient, payment.NewFakeProvider())
 
 	birthday, _ := time.Parse(dateLayoutFmt, "13/11/1923")
 	launchDate, _ := time.Parse(dateLayoutFmt, "06/04/2021")
@@ -368,7 +381,7 @@ func TestMakeBookingFromSameLaunchpadSameDestinationNextWeek(t *testing.T) {
 	}
 
 	spaceClient := &SpaceXMockAvailable{}
-	srv := NewBookingService(store, spaceClient)
+	srv := NewBookingService(store, spaceClient, payment.NewFakeProvider())
 
 	birthday, _ := time.Parse(dateLayoutFmt, "13/11/1923")
 	launchDate, _ := time.Parse(dateLayoutFmt, "06/04/2021")
@@ -424,7 +437,7 @@ func TestMakeBookingWithNoSpaceXAvailability(t *testing.T) {
 	}
 
 	spaceClient := &SpaceXMockUnAvailable{}
-	srv := NewBookingService(store, spaceClient)
+	srv := NewBookingService(store, spaceClient, payment.NewFakeProvider())
 
 	birthday, _ := time.Parse(dateLayoutFmt, "13/11/1923")
 	launchDate, _ := time.Parse(dateLayoutFmt, "06/04/2021")
@@ -460,7 +473,7 @@ func TestMakeBoookingWithSpaceXReturnError(t *testing.T) {
 	}
 
 	spaceClient := &SpaceXMockError{}
-	srv := NewBookingService(store, spaceClient)
+	srv := NewBookingService(store, spaceClient, payment.NewFakeProvider())
 
 	birthday, _ := time.Parse(dateLayoutFmt, "13/11/1923")
 	launchDate, _ := time.Parse(dateLayoutFmt, "06/04/2021")
@@ -480,3 +493,1519 @@ func TestMakeBoookingWithSpaceXReturnError(t *testing.T) {
 		return
 	}
 }
+
+func newTestBookingRequest(destinationID string) BookingRequest {
+	birthday, _ := time.Parse(dateLayoutFmt, "1923-11-13")
+	launchDate, _ := time.Parse(dateLayoutFmt, "2049-04-06")
+	return BookingRequest{
+		FirstName:     "Giorgos",
+		LastName:      "Papadopoulos",
+		Gender:        "male",
+		Birthday:      Date{Time: birthday},
+		LaunchpadID:   genLaunchId(),
+		DestinationID: destinationID,
+		LaunchDate:    Date{Time: launchDate},
+	}
+}
+
+func TestCancelBooking(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider())
+
+	newBooking, err := srv.MakeBooking(context.Background(), newTestBookingRequest(availableDestinations[0].ID.String()))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	cancelled, err := srv.CancelBooking(context.Background(), newBooking.ID.String(), CancelBookingRequest{Reason: "sick"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if cancelled.Status != entity.BookingStatusCancelled || cancelled.CancelledAt == nil || cancelled.CancelReason != "sick" {
+		t.Errorf("expected cancelled booking with reason but got %+v", cancelled.Booking)
+		return
+	}
+
+	_, err = srv.CancelBooking(context.Background(), newBooking.ID.String(), CancelBookingRequest{})
+	if err != ErrBookingNotCancelable {
+		t.Errorf("expected %v but got %v", ErrBookingNotCancelable, err)
+		return
+	}
+
+	_, err = srv.CancelBooking(context.Background(), uuid.New().String(), CancelBookingRequest{})
+	if err != ErrBookingNotFound {
+		t.Errorf("expected %v but got %v", ErrBookingNotFound, err)
+		return
+	}
+}
+
+func TestCancelLastBookingFreesLaunchpad(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider())
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	first, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	req2 := req
+	req2.FirstName = "John"
+	second, err := srv.MakeBooking(context.Background(), req2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	otherDst := req
+	otherDst.DestinationID = availableDestinations[1].ID.String()
+
+	cancelled, err := srv.CancelBooking(context.Background(), first.ID.String(), CancelBookingRequest{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if cancelled.Flight.Status != entity.FlightStatusScheduled {
+		t.Errorf("expected flight to stay scheduled while it has active bookings")
+		return
+	}
+	if _, err := srv.MakeBooking(context.Background(), otherDst); err != ErrLaunchPadUnavailable {
+		t.Errorf("expected %v but got %v", ErrLaunchPadUnavailable, err)
+		return
+	}
+
+	cancelled, err = srv.CancelBooking(context.Background(), second.ID.String(), CancelBookingRequest{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if cancelled.Flight.Status != entity.FlightStatusCancelled {
+		t.Errorf("expected flight to be cancelled with its last booking")
+		return
+	}
+	if _, err := srv.MakeBooking(context.Background(), otherDst); err != nil {
+		t.Errorf("expected launchpad to be free but got %v", err)
+		return
+	}
+}
+
+func TestGetBooking(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider())
+
+	newBooking, err := srv.MakeBooking(context.Background(), newTestBookingRequest(availableDestinations[0].ID.String()))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	found, err := srv.GetBooking(context.Background(), newBooking.ID.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if found.ID != newBooking.ID || found.User.ID != newBooking.User.ID || found.Flight.ID != newBooking.Flight.ID {
+		t.Errorf("expected %+v but got %+v", newBooking.Booking, found.Booking)
+		return
+	}
+
+	if _, err := srv.GetBooking(context.Background(), uuid.New().String()); err != ErrBookingNotFound {
+		t.Errorf("expected %v but got %v", ErrBookingNotFound, err)
+		return
+	}
+	if _, err := srv.GetBooking(context.Background(), "not-a-uuid"); err != ErrInvalidUUID {
+		t.Errorf("expected %v but got %v", ErrInvalidUUID, err)
+		return
+	}
+}
+
+func TestMakeBookingWhenFlightIsFull(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider(), WithCapacityPolicy(CapacityPolicy{Default: 2}))
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	first, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if first.Flight.Capacity != 2 {
+		t.Errorf("expected flight capacity 2 but got %d", first.Flight.Capacity)
+		return
+	}
+	req.FirstName = "John"
+	if _, err := srv.MakeBooking(context.Background(), req); err != nil {
+		t.Error(err)
+		return
+	}
+	req.FirstName = "Maria"
+	if _, err := srv.MakeBooking(context.Background(), req); err != ErrFlightFull {
+		t.Errorf("expected %v but got %v", ErrFlightFull, err)
+		return
+	}
+
+	if _, err := srv.CancelBooking(context.Background(), first.ID.String(), CancelBookingRequest{}); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.MakeBooking(context.Background(), req); err != nil {
+		t.Errorf("expected a seat to be free after cancellation but got %v", err)
+		return
+	}
+
+	cnt, ok, err := checkBookingCount(db, 3)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !ok {
+		t.Errorf("expected 3 bookings but got %d", cnt)
+		return
+	}
+}
+
+func TestCapacityPolicy(t *testing.T) {
+	p := CapacityPolicy{
+		Default:       5,
+		ByLaunchpad:   map[string]int{"pad": 3},
+		ByDestination: map[string]int{"dst": 7},
+	}
+	if c := p.For("pad", "dst"); c != 3 {
+		t.Errorf("expected launchpad capacity 3 but got %d", c)
+	}
+	if c := p.For("other", "dst"); c != 7 {
+		t.Errorf("expected destination capacity 7 but got %d", c)
+	}
+	if c := p.For("other", "other"); c != 5 {
+		t.Errorf("expected default capacity 5 but got %d", c)
+	}
+	if c := (CapacityPolicy{}).For("pad", "dst"); c != DefaultFlightCapacity {
+		t.Errorf("expected capacity %d but got %d", DefaultFlightCapacity, c)
+	}
+}
+
+func TestWaitlistPromotedOnCancellation(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider(), WithCapacityPolicy(CapacityPolicy{Default: 1}))
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	first, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	waiting := req
+	waiting.FirstName = "John"
+	waiting.Waitlist = true
+	if _, err := srv.MakeBooking(context.Background(), waiting); !CanWaitlist(err) {
+		t.Errorf("expected a waitlistable error but got %v", err)
+		return
+	}
+	entry, err := srv.JoinWaitlist(context.Background(), waiting)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if entry.Status != entity.WaitlistStatusWaiting {
+		t.Errorf("expected entry to be waiting but got %s", entry.Status)
+		return
+	}
+	again := waiting
+	again.FirstName = "JOHN"
+	if _, err := srv.JoinWaitlist(context.Background(), again); err != ErrAlreadyWaiting {
+		t.Errorf("expected %v but got %v", ErrAlreadyWaiting, err)
+		return
+	}
+
+	if _, err := srv.CancelBooking(context.Background(), first.ID.String(), CancelBookingRequest{}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	entry, err = srv.GetWaitlistEntry(context.Background(), entry.ID.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if entry.Status != entity.WaitlistStatusPromoted || entry.BookingID == nil {
+		t.Errorf("expected entry to be promoted but got %+v", entry.WaitlistEntry)
+		return
+	}
+	promoted, err := srv.GetBooking(context.Background(), entry.BookingID.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if promoted.User.FirstName != "John" || promoted.Status != entity.BookingStatusActive || promoted.ExpiresAt != nil {
+		t.Errorf("expected an active booking for John but got %+v", promoted.Booking)
+		return
+	}
+}
+
+func TestWaitlistSkipsBookedPassenger(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider(), WithCapacityPolicy(CapacityPolicy{Default: 1}))
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	first, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.JoinWaitlist(context.Background(), req); err != ErrAlreadyBooked {
+		t.Errorf("expected %v but got %v", ErrAlreadyBooked, err)
+		return
+	}
+
+	john, maria := req, req
+	john.FirstName, maria.FirstName = "John", "Maria"
+	johnEntry, err := srv.JoinWaitlist(context.Background(), john)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	mariaEntry, err := srv.JoinWaitlist(context.Background(), maria)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// John gets a seat of his own while waiting
+	if _, err := db.Exec(context.Background(), `UPDATE flights SET capacity = 2 WHERE id = $1`, first.Flight.ID); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.MakeBooking(context.Background(), john); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.CancelBooking(context.Background(), first.ID.String(), CancelBookingRequest{}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for entry, status := range map[string]string{
+		johnEntry.ID.String():  entity.WaitlistStatusStale,
+		mariaEntry.ID.String(): entity.WaitlistStatusPromoted,
+	} {
+		got, err := srv.GetWaitlistEntry(context.Background(), entry)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if got.Status != status {
+			t.Errorf("expected %v but got %v", status, got.Status)
+			return
+		}
+	}
+}
+
+func TestMakeBookingSamePassengerTwice(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider())
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	first, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	again := req
+	again.FirstName = strings.ToUpper(req.FirstName)
+	if _, err := srv.MakeBooking(context.Background(), again); err != ErrAlreadyBooked {
+		t.Errorf("expected %v but got %v", ErrAlreadyBooked, err)
+		return
+	}
+
+	byID := BookingRequest{
+		PassengerID:   first.User.ID.String(),
+		LaunchpadID:   req.LaunchpadID,
+		DestinationID: req.DestinationID,
+		LaunchDate:    req.LaunchDate,
+	}
+	if _, err := srv.MakeBooking(context.Background(), byID); err != ErrAlreadyBooked {
+		t.Errorf("expected %v but got %v", ErrAlreadyBooked, err)
+		return
+	}
+
+	if _, err := srv.CancelBooking(context.Background(), first.ID.String(), CancelBookingRequest{}); err != nil {
+		t.Error(err)
+		return
+	}
+	rebooked, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Errorf("expected to book again after cancelling but got %v", err)
+		return
+	}
+	if rebooked.User.ID != first.User.ID {
+		t.Errorf("expected the same passenger %s but got %s", first.User.ID, rebooked.User.ID)
+		return
+	}
+}
+
+func TestMakeBookingReusesPassenger(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider())
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	first, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	nextWeek := req
+	nextWeek.LaunchDate = Date{Time: req.LaunchDate.AddDate(0, 0, 7)}
+	second, err := srv.MakeBooking(context.Background(), nextWeek)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if second.User.ID != first.User.ID {
+		t.Errorf("expected the same passenger %s but got %s", first.User.ID, second.User.ID)
+		return
+	}
+
+	byID := BookingRequest{
+		PassengerID:   first.User.ID.String(),
+		LaunchpadID:   genLaunchId(),
+		DestinationID: req.DestinationID,
+		LaunchDate:    req.LaunchDate,
+	}
+	third, err := srv.MakeBooking(context.Background(), byID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if third.User.ID != first.User.ID || third.User.FirstName != req.FirstName {
+		t.Errorf("expected passenger %+v but got %+v", first.User, third.User)
+		return
+	}
+
+	byID.PassengerID = uuid.New().String()
+	if _, err := srv.MakeBooking(context.Background(), byID); err != ErrPassengerNotFound {
+		t.Errorf("expected %v but got %v", ErrPassengerNotFound, err)
+		return
+	}
+}
+
+func TestPassengerBookings(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider())
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	first, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	nextWeek := req
+	nextWeek.LaunchDate = Date{Time: req.LaunchDate.AddDate(0, 0, 7)}
+	if _, err := srv.MakeBooking(context.Background(), nextWeek); err != nil {
+		t.Error(err)
+		return
+	}
+	other := newTestBookingRequest(availableDestinations[1].ID.String())
+	other.FirstName = "Other"
+	if _, err := srv.MakeBooking(context.Background(), other); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.CancelBooking(context.Background(), first.ID.String(), CancelBookingRequest{}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	passengerID := first.User.ID.String()
+	passenger, err := srv.GetPassenger(context.Background(), passengerID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if passenger.FirstName != req.FirstName {
+		t.Errorf("expected %v but got %v", req.FirstName, passenger.FirstName)
+		return
+	}
+
+	page, err := srv.PassengerBookings(context.Background(), passengerID, GetBookingsReq{Limit: 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(page.Bookings) != 1 || page.Bookings[0].ID != first.ID {
+		t.Errorf("expected booking %s but got %+v", first.ID, page.Bookings)
+		return
+	}
+	if page.Bookings[0].Status != entity.BookingStatusCancelled {
+		t.Errorf("expected %v but got %v", entity.BookingStatusCancelled, page.Bookings[0].Status)
+		return
+	}
+
+	page, err = srv.PassengerBookings(context.Background(), passengerID, GetBookingsReq{Limit: 10, Cursor: page.Cursor})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(page.Bookings) != 1 || page.Bookings[0].User.ID != first.User.ID {
+		t.Errorf("expected one more booking of %s but got %+v", passengerID, page.Bookings)
+		return
+	}
+
+	if _, err := srv.PassengerBookings(context.Background(), uuid.New().String(), GetBookingsReq{Limit: 10}); err != ErrPassengerNotFound {
+		t.Errorf("expected %v but got %v", ErrPassengerNotFound, err)
+		return
+	}
+	if _, err := srv.GetPassenger(context.Background(), "invalid"); err != ErrInvalidUUID {
+		t.Errorf("expected %v but got %v", ErrInvalidUUID, err)
+		return
+	}
+}
+
+func TestHoldConfirmAndExpire(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider(), WithCapacityPolicy(CapacityPolicy{Default: 1}))
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	held, err := srv.HoldBooking(context.Background(), HoldRequest{BookingRequest: req})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if held.Status != entity.BookingStatusHeld || held.ExpiresAt == nil {
+		t.Errorf("expected a held booking with an expiry but got %+v", held.Booking)
+		return
+	}
+	other := req
+	other.FirstName = "John"
+	if _, err := srv.MakeBooking(context.Background(), other); err != ErrFlightFull {
+		t.Errorf("expected %v but got %v", ErrFlightFull, err)
+		return
+	}
+	confirmed, err := srv.ConfirmBooking(context.Background(), held.ID.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if confirmed.Status != entity.BookingStatusActive || confirmed.ExpiresAt != nil {
+		t.Errorf("expected an active booking but got %+v", confirmed.Booking)
+		return
+	}
+	if _, err := srv.ConfirmBooking(context.Background(), held.ID.String()); err != ErrBookingNotConfirmable {
+		t.Errorf("expected %v but got %v", ErrBookingNotConfirmable, err)
+		return
+	}
+
+	req2 := newTestBookingRequest(availableDestinations[1].ID.String())
+	held, err = srv.HoldBooking(context.Background(), HoldRequest{BookingRequest: req2, Minutes: 5})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := db.Exec(context.Background(),
+		`UPDATE bookings SET expires_at = now() - interval '1 minute' WHERE id = $1`, held.ID); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.ConfirmBooking(context.Background(), held.ID.String()); err != ErrHoldExpired {
+		t.Errorf("expected %v but got %v", ErrHoldExpired, err)
+		return
+	}
+	n, err := srv.ExpireHolds(context.Background())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if n != 1 {
+		t.Errorf("expected %v but got %v", 1, n)
+		return
+	}
+	expired, err := srv.GetBooking(context.Background(), held.ID.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if expired.Status != entity.BookingStatusExpired || expired.Flight.Status != entity.FlightStatusCancelled {
+		t.Errorf("expected an expired booking on a cancelled flight but got %+v", expired.Booking)
+		return
+	}
+	// the launchpad is free again
+	if _, err := srv.MakeBooking(context.Background(), req2); err != nil {
+		t.Errorf("expected launchpad to be free but got %v", err)
+		return
+	}
+}
+
+func TestUnpaidBookingExpires(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider(),
+		WithCapacityPolicy(CapacityPolicy{Default: 1}),
+		WithPaymentWindow(10*time.Minute),
+	)
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	unpaid, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if unpaid.Status != entity.BookingStatusPendingPayment || unpaid.ExpiresAt == nil ||
+		unpaid.ExpiresAt.After(time.Now().Add(10*time.Minute)) {
+		t.Errorf("expected a pending payment booking with a deadline but got %+v", unpaid.Booking)
+		return
+	}
+	other := req
+	other.FirstName = "John"
+	if _, err := srv.MakeBooking(context.Background(), other); err != ErrFlightFull {
+		t.Errorf("expected %v but got %v", ErrFlightFull, err)
+		return
+	}
+
+	if _, err := db.Exec(context.Background(),
+		`UPDATE bookings SET expires_at = now() - interval '1 minute' WHERE id = $1`, unpaid.ID); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.ConfirmBooking(context.Background(), unpaid.ID.String()); err != ErrHoldExpired {
+		t.Errorf("expected %v but got %v", ErrHoldExpired, err)
+		return
+	}
+	n, err := srv.ExpireHolds(context.Background())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if n != 1 {
+		t.Errorf("expected %v but got %v", 1, n)
+		return
+	}
+	expired, err := srv.GetBooking(context.Background(), unpaid.ID.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if expired.Status != entity.BookingStatusExpired {
+		t.Errorf("expected %v but got %v", entity.BookingStatusExpired, expired.Status)
+		return
+	}
+	// the seat is free again
+	if _, err := srv.MakeBooking(context.Background(), other); err != nil {
+		t.Errorf("expected the seat to be free but got %v", err)
+	}
+}
+
+func TestMakeGroupBookingAllOrNothing(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider(), WithCapacityPolicy(CapacityPolicy{Default: 3}))
+
+	single := newTestBookingRequest(availableDestinations[0].ID.String())
+	group := GroupBookingRequest{
+		Passengers: []GroupPassenger{
+			{FirstName: "John", LastName: "Doe", Gender: "male", Birthday: single.Birthday},
+			{FirstName: "Jane", LastName: "Doe", Gender: "female", Birthday: single.Birthday},
+		},
+		LaunchpadID:   single.LaunchpadID,
+		DestinationID: single.DestinationID,
+		LaunchDate:    single.LaunchDate,
+	}
+	ans, err := srv.MakeGroupBooking(context.Background(), group)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(ans.Bookings) != 2 || ans.Bookings[0].Flight.ID != ans.Bookings[1].Flight.ID {
+		t.Errorf("expected 2 bookings on the same flight but got %+v", ans.Bookings)
+		return
+	}
+
+	// a single seat is left, the group of two does not fit
+	bigger := group
+	bigger.Passengers = []GroupPassenger{
+		{FirstName: "Jim", LastName: "Roe", Gender: "male", Birthday: single.Birthday},
+		{FirstName: "Jill", LastName: "Roe", Gender: "female", Birthday: single.Birthday},
+	}
+	if _, err := srv.MakeGroupBooking(context.Background(), bigger); err != ErrFlightFull {
+		t.Errorf("expected %v but got %v", ErrFlightFull, err)
+		return
+	}
+	cnt, ok, err := checkBookingCount(db, 2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !ok {
+		t.Errorf("expected %v bookings but got %v", 2, cnt)
+		return
+	}
+
+	if _, err := srv.MakeBooking(context.Background(), single); err != nil {
+		t.Errorf("expected the last seat to be booked but got %v", err)
+		return
+	}
+}
+
+func TestMakeGroupBookingPricesEverySeat(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider(), WithCapacityPolicy(CapacityPolicy{Default: 2}))
+
+	single := newTestBookingRequest(availableDestinations[0].ID.String())
+	group := GroupBookingRequest{
+		Passengers: []GroupPassenger{
+			{FirstName: "John", LastName: "Doe", Gender: "male", Birthday: single.Birthday},
+			{FirstName: "Jane", LastName: "Doe", Gender: "female", Birthday: single.Birthday},
+		},
+		LaunchpadID:   single.LaunchpadID,
+		DestinationID: single.DestinationID,
+		LaunchDate:    single.LaunchDate,
+	}
+	ans, err := srv.MakeGroupBooking(context.Background(), group)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// the second seat is booked on a flight half full
+	if len(ans.Bookings) != 2 || ans.Bookings[1].Price <= ans.Bookings[0].Price {
+		t.Errorf("expected the second seat to cost more than the first but got %+v", ans.Bookings)
+		return
+	}
+}
+
+func TestQuoteAndBookingPrice(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	fares := pricing.Policy{BaseFare: 50000}
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider(),
+		WithCapacityPolicy(CapacityPolicy{Default: 2}), WithPricing(fares))
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	quoteReq := QuoteRequest{
+		Birthday:      req.Birthday,
+		LaunchpadID:   req.LaunchpadID,
+		DestinationID: req.DestinationID,
+		LaunchDate:    req.LaunchDate,
+	}
+	quote, err := srv.Quote(context.Background(), quoteReq)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// far in the future and a senior
+	if quote.Price != 50000*55/100 {
+		t.Errorf("expected %v but got %v", 50000*55/100, quote.Price)
+		return
+	}
+	first, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if first.Price != quote.Price {
+		t.Errorf("expected %v but got %v", quote.Price, first.Price)
+		return
+	}
+
+	// the flight is half full now
+	quote, err = srv.Quote(context.Background(), quoteReq)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if quote.Price != 50000*70/100 {
+		t.Errorf("expected %v but got %v", 50000*70/100, quote.Price)
+		return
+	}
+	stored, err := srv.GetBooking(context.Background(), first.ID.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if stored.Price != first.Price {
+		t.Errorf("expected %v but got %v", first.Price, stored.Price)
+		return
+	}
+
+	quoteReq.PassengerID = uuid.New().String()
+	if _, err := srv.Quote(context.Background(), quoteReq); err != ErrPassengerNotFound {
+		t.Errorf("expected %v but got %v", ErrPassengerNotFound, err)
+		return
+	}
+}
+
+func TestConfirmCapturesAndCancelRefunds(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payments := payment.NewFakeProvider()
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payments)
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	booked, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if booked.Status != entity.BookingStatusPendingPayment {
+		t.Errorf("expected %v but got %v", entity.BookingStatusPendingPayment, booked.Status)
+		return
+	}
+	confirmed, err := srv.ConfirmBooking(context.Background(), booked.ID.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if confirmed.Status != entity.BookingStatusActive {
+		t.Errorf("expected %v but got %v", entity.BookingStatusActive, confirmed.Status)
+		return
+	}
+	capture, err := store.CapturedPayment(context.Background(), booked.ID.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if capture.Amount != booked.Price {
+		t.Errorf("expected %v but got %v", booked.Price, capture.Amount)
+		return
+	}
+
+	if _, err := srv.CancelBooking(context.Background(), booked.ID.String(), CancelBookingRequest{}); err != nil {
+		t.Error(err)
+		return
+	}
+	if got := payments.Refunded(capture.ProviderRef); got != booked.Price {
+		t.Errorf("expected %v refunded but got %v", booked.Price, got)
+		return
+	}
+	var refunds int
+	if err := db.QueryRow(context.Background(),
+		`SELECT count(1) FROM payments WHERE booking_id = $1 AND kind = $2`,
+		booked.ID, entity.PaymentKindRefund).Scan(&refunds); err != nil {
+		t.Error(err)
+		return
+	}
+	if refunds != 1 {
+		t.Errorf("expected %v but got %v", 1, refunds)
+		return
+	}
+
+	// a declined payment leaves the booking pending
+	payments.DeclineAbove = 1
+	other := req
+	other.LaunchpadID = genLaunchId()
+	booked, err = srv.MakeBooking(context.Background(), other)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.ConfirmBooking(context.Background(), booked.ID.String()); err != ErrPaymentFailed {
+		t.Errorf("expected %v but got %v", ErrPaymentFailed, err)
+		return
+	}
+	pending, err := srv.GetBooking(context.Background(), booked.ID.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if pending.Status != entity.BookingStatusPendingPayment {
+		t.Errorf("expected %v but got %v", entity.BookingStatusPendingPayment, pending.Status)
+		return
+	}
+}
+
+func TestRetryRefunds(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payments := &PaymentMockRefundError{FakeProvider: payment.NewFakeProvider(), failRefunds: true}
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payments)
+
+	booked, err := srv.MakeBooking(context.Background(), newTestBookingRequest(availableDestinations[0].ID.String()))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.ConfirmBooking(context.Background(), booked.ID.String()); err != nil {
+		t.Error(err)
+		return
+	}
+	// the cancellation succeeds even though the refund fails
+	if _, err := srv.CancelBooking(context.Background(), booked.ID.String(), CancelBookingRequest{}); err != nil {
+		t.Error(err)
+		return
+	}
+	pending, err := store.PendingRefunds(context.Background())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(pending) != 1 || pending[0].BookingID != booked.ID || pending[0].Amount != booked.Price {
+		t.Errorf("expected a pending refund of booking %v but got %+v", booked.ID, pending)
+		return
+	}
+
+	n, err := srv.RetryRefunds(context.Background())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if n != 0 {
+		t.Errorf("expected %v but got %v", 0, n)
+		return
+	}
+
+	payments.failRefunds = false
+	n, err = srv.RetryRefunds(context.Background())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if n != 1 {
+		t.Errorf("expected %v but got %v", 1, n)
+		return
+	}
+	if got := payments.Refunded(pending[0].ProviderRef); got != booked.Price {
+		t.Errorf("expected %v refunded but got %v", booked.Price, got)
+		return
+	}
+	pending, err = store.PendingRefunds(context.Background())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected no pending refund but got %+v", pending)
+		return
+	}
+	var refunds int
+	if err := db.QueryRow(context.Background(),
+		`SELECT count(1) FROM payments WHERE booking_id = $1 AND kind = $2`,
+		booked.ID, entity.PaymentKindRefund).Scan(&refunds); err != nil {
+		t.Error(err)
+		return
+	}
+	if refunds != 1 {
+		t.Errorf("expected %v but got %v", 1, refunds)
+	}
+}
+
+func TestRebookBooking(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider(), WithCapacityPolicy(CapacityPolicy{Default: 1}))
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	booked, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	nextWeek := Date{Time: req.LaunchDate.AddDate(0, 0, 7)}
+	moved, err := srv.RebookBooking(context.Background(), booked.ID.String(), RebookRequest{LaunchDate: nextWeek})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if moved.ID != booked.ID || moved.Flight.ID == booked.Flight.ID || !moved.Flight.Date.Equal(nextWeek.Time) {
+		t.Errorf("expected booking %s on a flight on %s but got %+v", booked.ID, nextWeek, moved.Booking)
+		return
+	}
+	if moved.Status != booked.Status || moved.Price != booked.Price {
+		t.Errorf("expected status and price to be kept but got %+v", moved.Booking)
+		return
+	}
+	cnt, ok, err := checkBookingCount(db, 1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !ok {
+		t.Errorf("expected %v bookings but got %v", 1, cnt)
+		return
+	}
+
+	// the first flight was released, its launchpad can fly elsewhere that date
+	otherDst := req
+	otherDst.FirstName = "John"
+	otherDst.DestinationID = availableDestinations[1].ID.String()
+	if _, err := srv.MakeBooking(context.Background(), otherDst); err != nil {
+		t.Errorf("expected launchpad to be free but got %v", err)
+		return
+	}
+
+	// the flight of John is full
+	if _, err := srv.RebookBooking(context.Background(), booked.ID.String(), RebookRequest{
+		DestinationID: otherDst.DestinationID,
+		LaunchDate:    req.LaunchDate,
+	}); err != ErrFlightFull {
+		t.Errorf("expected %v but got %v", ErrFlightFull, err)
+		return
+	}
+
+	if _, err := srv.CancelBooking(context.Background(), booked.ID.String(), CancelBookingRequest{}); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.RebookBooking(context.Background(), booked.ID.String(), RebookRequest{LaunchDate: req.LaunchDate}); err != ErrBookingNotModifiable {
+		t.Errorf("expected %v but got %v", ErrBookingNotModifiable, err)
+		return
+	}
+}
+
+func TestRebookReleasesOwnFlight(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider())
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	booked, err := srv.MakeBooking(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// alone on the flight, the launchpad can fly elsewhere that date
+	otherDst := availableDestinations[1].ID.String()
+	moved, err := srv.RebookBooking(context.Background(), booked.ID.String(), RebookRequest{DestinationID: otherDst})
+	if err != nil {
+		t.Errorf("expected the booking to move to another destination but got %v", err)
+		return
+	}
+	// and the destination can fly another day of the week
+	nextDay := Date{Time: req.LaunchDate.AddDate(0, 0, 1)}
+	moved, err = srv.RebookBooking(context.Background(), booked.ID.String(), RebookRequest{LaunchDate: nextDay})
+	if err != nil {
+		t.Errorf("expected the booking to move to another day of the week but got %v", err)
+		return
+	}
+
+	// with another passenger on the flight it keeps the launchpad
+	other := req
+	other.FirstName = "John"
+	other.DestinationID = otherDst
+	other.LaunchDate = nextDay
+	if _, err := srv.MakeBooking(context.Background(), other); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.RebookBooking(context.Background(), booked.ID.String(), RebookRequest{
+		DestinationID: req.DestinationID,
+	}); err != ErrLaunchPadUnavailable {
+		t.Errorf("expected %v but got %v", ErrLaunchPadUnavailable, err)
+		return
+	}
+	if moved.Flight.Destination.ID.String() != otherDst || !moved.Flight.Date.Equal(nextDay.Time) {
+		t.Errorf("expected a flight to %s on %s but got %+v", otherDst, nextDay, moved.Flight)
+	}
+}
+
+func TestBookingHistory(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider())
+	ctx := entity.WithActor(context.Background(), "agent-42")
+
+	req := newTestBookingRequest(availableDestinations[0].ID.String())
+	booked, err := srv.MakeBooking(ctx, req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.ConfirmBooking(ctx, booked.ID.String()); err != nil {
+		t.Error(err)
+		return
+	}
+	moved, err := srv.RebookBooking(ctx, booked.ID.String(), RebookRequest{
+		LaunchDate: Date{Time: req.LaunchDate.AddDate(0, 0, 7)},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.CancelBooking(context.Background(), booked.ID.String(), CancelBookingRequest{Reason: "sick"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	ans, err := srv.BookingHistory(context.Background(), booked.ID.String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := []entity.BookingEvent{
+		{Kind: entity.BookingEventCreated, ToStatus: entity.BookingStatusPendingPayment, FlightID: booked.Flight.ID, Actor: "agent-42"},
+		{Kind: entity.BookingEventConfirmed, FromStatus: entity.BookingStatusPendingPayment, ToStatus: entity.BookingStatusActive, FlightID: booked.Flight.ID, Actor: "agent-42"},
+		{Kind: entity.BookingEventRebooked, FromStatus: entity.BookingStatusActive, ToStatus: entity.BookingStatusActive, FlightID: moved.Flight.ID, Actor: "agent-42"},
+		{Kind: entity.BookingEventCancelled, FromStatus: entity.BookingStatusActive, ToStatus: entity.BookingStatusCancelled, FlightID: moved.Flight.ID, Details: "sick", Actor: entity.DefaultActor},
+	}
+	if len(ans.Events) != len(expected) {
+		t.Errorf("expected %v events but got %+v", len(expected), ans.Events)
+		return
+	}
+	for i, e := range expected {
+		got := ans.Events[i]
+		if got.Kind != e.Kind || got.FromStatus != e.FromStatus || got.ToStatus != e.ToStatus ||
+			got.FlightID != e.FlightID || got.Actor != e.Actor || (e.Details != "" && got.Details != e.Details) {
+			t.Errorf("expected %+v but got %+v", e, got)
+			return
+		}
+	}
+	if ans.Events[2].PreviousFlightID == nil || *ans.Events[2].PreviousFlightID != booked.Flight.ID {
+		t.Errorf("expected previous flight %s but got %v", booked.Flight.ID, ans.Events[2].PreviousFlightID)
+		return
+	}
+
+	for _, q := range []string{
+		`UPDATE booking_events SET actor = 'someone else'`,
+		`DELETE FROM booking_events`,
+		`TRUNCATE booking_events`,
+	} {
+		if _, err := db.Exec(context.Background(), q); err == nil {
+			t.Errorf("expected booking history to be append-only but %q succeeded", q)
+			return
+		}
+	}
+}
+
+func TestAllBookingsFilterAndSort(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider())
+
+	var made []BookingResponse
+	for i, name := range []string{"Anna", "Maria", "Eleni"} {
+		req := newTestBookingRequest(availableDestinations[i%2].ID.String())
+		req.FirstName = name
+		req.LaunchDate = Date{Time: req.LaunchDate.AddDate(0, 0, 7*(2-i))}
+		b, err := srv.MakeBooking(context.Background(), req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		made = append(made, b)
+	}
+	if _, err := srv.CancelBooking(context.Background(), made[1].ID.String(), CancelBookingRequest{}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	page, err := srv.AllBookings(context.Background(), GetBookingsReq{Limit: 10, Passenger: "ELE"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(page.Bookings) != 1 || page.Bookings[0].ID != made[2].ID {
+		t.Errorf("expected booking %s but got %+v", made[2].ID, page.Bookings)
+		return
+	}
+
+	page, err = srv.AllBookings(context.Background(), GetBookingsReq{Limit: 10, Status: entity.BookingStatusCancelled})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(page.Bookings) != 1 || page.Bookings[0].ID != made[1].ID {
+		t.Errorf("expected booking %s but got %+v", made[1].ID, page.Bookings)
+		return
+	}
+
+	page, err = srv.AllBookings(context.Background(), GetBookingsReq{Limit: 10, DestinationID: availableDestinations[0].ID.String()})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(page.Bookings) != 2 {
+		t.Errorf("expected %v but got %v", 2, len(page.Bookings))
+		return
+	}
+
+	// the first booking launches last
+	req := GetBookingsReq{Limit: 2, Sort: "-" + entity.BookingSortLaunchDate}
+	page, err = srv.AllBookings(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(page.Bookings) != 2 || page.Bookings[0].ID != made[0].ID || page.Bookings[1].ID != made[1].ID {
+		t.Errorf("expected bookings %s, %s but got %+v", made[0].ID, made[1].ID, page.Bookings)
+		return
+	}
+	req.Cursor = page.Cursor
+	page, err = srv.AllBookings(context.Background(), req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(page.Bookings) != 1 || page.Bookings[0].ID != made[2].ID {
+		t.Errorf("expected booking %s but got %+v", made[2].ID, page.Bookings)
+		return
+	}
+}
+
+func TestAllBookingsCursors(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider(), WithCursorSecret([]byte("secret")))
+
+	var made []BookingResponse
+	for i := 0; i < 3; i++ {
+		b, err := srv.MakeBooking(context.Background(), newTestBookingRequest(availableDestinations[0].ID.String()))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		made = append(made, b)
+	}
+
+	first, err := srv.AllBookings(context.Background(), GetBookingsReq{Limit: 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if first.PrevCursor != "" {
+		t.Errorf("expected no previous cursor but got %v", first.PrevCursor)
+		return
+	}
+	second, err := srv.AllBookings(context.Background(), GetBookingsReq{Limit: 1, Cursor: first.Cursor})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(second.Bookings) != 1 || second.Bookings[0].ID != made[1].ID {
+		t.Errorf("expected booking %s but got %+v", made[1].ID, second.Bookings)
+		return
+	}
+	back, err := srv.AllBookings(context.Background(), GetBookingsReq{Limit: 1, Cursor: second.PrevCursor})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(back.Bookings) != 1 || back.Bookings[0].ID != made[0].ID {
+		t.Errorf("expected booking %s but got %+v", made[0].ID, back.Bookings)
+		return
+	}
+
+	// the cursor was issued without filters
+	_, err = srv.AllBookings(context.Background(), GetBookingsReq{Limit: 1, Cursor: first.Cursor, Status: entity.BookingStatusActive})
+	if err != ErrInvalidCursor {
+		t.Errorf("expected %v but got %v", ErrInvalidCursor, err)
+		return
+	}
+	other := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider(), WithCursorSecret([]byte("other")))
+	if _, err := other.AllBookings(context.Background(), GetBookingsReq{Limit: 1, Cursor: first.Cursor}); err != ErrInvalidCursor {
+		t.Errorf("expected %v but got %v", ErrInvalidCursor, err)
+		return
+	}
+}
+
+func TestAllBookingsPaginationWithTies(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+	availableDestinations, err := createDestinations(store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewBookingService(store, &SpaceXMockAvailable{}, payment.NewFakeProvider())
+
+	for i := 0; i < 4; i++ {
+		if _, err := srv.MakeBooking(context.Background(), newTestBookingRequest(availableDestinations[0].ID.String())); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	// bookings created at the same time used to be skipped unless their id was greater
+	if _, err := db.Exec(context.Background(), "UPDATE bookings SET created_at = '2049-01-01T00:00:00Z'"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	req := GetBookingsReq{Limit: 1, Total: TotalExact}
+	for {
+		page, err := srv.AllBookings(context.Background(), req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if page.Total == nil || *page.Total != 4 {
+			t.Errorf("expected a total of %v but got %v", 4, page.Total)
+			return
+		}
+		for _, b := range page.Bookings {
+			if seen[b.ID] {
+				t.Errorf("booking %s listed twice", b.ID)
+				return
+			}
+			seen[b.ID] = true
+		}
+		if !page.HasMore {
+			break
+		}
+		req.Cursor = page.Cursor
+	}
+	if len(seen) != 4 {
+		t.Errorf("expected %v but got %v", 4, len(seen))
+		return
+	}
+
+	page, err := srv.AllBookings(context.Background(), GetBookingsReq{Limit: 10, Total: TotalEstimate})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if page.HasMore || page.Total == nil || !page.TotalEstimated {
+		t.Errorf("expected an estimated total and no more bookings but got %+v", page)
+		return
+	}
+}
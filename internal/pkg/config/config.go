@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -19,6 +20,45 @@ type Config struct {
 	PgPasswd           string
 	PgPoolMaxConn      int
 	SpaceXUrl          string
+	// SpaceXCacheSize is the number of SpaceX responses cached in memory, fresh for their TTL
+	// and served for SpaceXCacheMaxStale more when the API fails.
+	SpaceXCacheSize     int
+	SpaceXLaunchpadTTL  time.Duration
+	SpaceXLaunchesTTL   time.Duration
+	SpaceXCacheMaxStale time.Duration
+	// SpaceXCachePersist keeps the SpaceX responses in postgres as well.
+	SpaceXCachePersist bool
+	// SpaceXMaxAttempts is the number of attempts of a SpaceX request failing with
+	// a network error, 429 or 5xx, with a backoff growing up to SpaceXRetryMaxDelay.
+	SpaceXMaxAttempts    int
+	SpaceXRetryBaseDelay time.Duration
+	SpaceXRetryMaxDelay  time.Duration
+	// SpaceXBreakerThreshold consecutive failed calls stop the calls to SpaceX
+	// for SpaceXBreakerOpenFor, 0 disables the circuit breaker.
+	SpaceXBreakerThreshold int
+	SpaceXBreakerOpenFor   time.Duration
+	// SpaceXSchedule checks the launchpads against the schedule synced every SpaceXSyncInterval
+	// by spacex-sync, calling the SpaceX API once the last sync is older than SpaceXScheduleMaxStale.
+	SpaceXSchedule         bool
+	SpaceXScheduleMaxStale time.Duration
+	SpaceXSyncInterval     time.Duration
+	// FlightCapacity is the number of seats of a new flight unless
+	// its launchpad or destination has a specific capacity.
+	FlightCapacity              int
+	FlightCapacityByLaunchpad   map[string]int
+	FlightCapacityByDestination map[string]int
+	// HoldSweepInterval is how often expired holds and unpaid bookings are released.
+	HoldSweepInterval time.Duration
+	// PaymentWindow is how long a new booking has to be paid.
+	PaymentWindow time.Duration
+	// IdempotencyPendingTTL frees the Idempotency-Key of a request that never completed,
+	// it is at least ServerWriteTimeout.
+	IdempotencyPendingTTL time.Duration
+	// BaseFare is the fare in cents of a destination without a specific one.
+	BaseFare              int64
+	BaseFareByDestination map[string]int64
+	// CursorSecret signs the pagination cursors, a random one is used when empty.
+	CursorSecret string
 }
 
 func (o *Config) DSN() string {
@@ -34,6 +74,27 @@ func NewConfig() *Config {
 		serverWriteTimeout time.Duration
 		serverReadTimeout  time.Duration
 		serverIdleTimeout  time.Duration
+		flightCapacity     int
+		launchpadCapacity  map[string]int
+		destCapacity       map[string]int
+		holdSweepInterval  time.Duration
+		paymentWindow      time.Duration
+		idempotencyTTL     time.Duration
+		baseFare           int64
+		destFares          map[string]int
+		spaceXCacheSize    int
+		launchpadTTL       time.Duration
+		launchesTTL        time.Duration
+		maxStale           time.Duration
+		persistCache       bool
+		maxAttempts        int
+		retryBaseDelay     time.Duration
+		retryMaxDelay      time.Duration
+		breakerThreshold   int
+		breakerOpenFor     time.Duration
+		useSchedule        bool
+		scheduleMaxStale   time.Duration
+		syncInterval       time.Duration
 	)
 	maxConns, err = strconv.Atoi(getEnvOrDefault("MAX_CONNS", "99"))
 	if err != nil {
@@ -53,6 +114,99 @@ func NewConfig() *Config {
 		panic(err)
 	}
 
+	flightCapacity, err = strconv.Atoi(getEnvOrDefault("FLIGHT_CAPACITY", "10"))
+	if err != nil {
+		panic(err)
+	}
+	launchpadCapacity, err = getIntMapFromEnv("FLIGHT_CAPACITY_LAUNCHPADS", "")
+	if err != nil {
+		panic(err)
+	}
+	destCapacity, err = getIntMapFromEnv("FLIGHT_CAPACITY_DESTINATIONS", "")
+	if err != nil {
+		panic(err)
+	}
+
+	holdSweepInterval, err = getDurationFromEnv("HOLD_SWEEP_INTERVAL", "30s")
+	if err != nil {
+		panic(err)
+	}
+	paymentWindow, err = getDurationFromEnv("PAYMENT_WINDOW", "30m")
+	if err != nil {
+		panic(err)
+	}
+	idempotencyTTL, err = getDurationFromEnv("IDEMPOTENCY_PENDING_TTL", "10m")
+	if err != nil {
+		panic(err)
+	}
+	if idempotencyTTL < serverWriteTimeout {
+		idempotencyTTL = serverWriteTimeout
+	}
+
+	baseFare, err = strconv.ParseInt(getEnvOrDefault("BASE_FARE", "100000"), 10, 64)
+	if err != nil {
+		panic(err)
+	}
+	destFares, err = getIntMapFromEnv("BASE_FARE_DESTINATIONS", "")
+	if err != nil {
+		panic(err)
+	}
+
+	spaceXCacheSize, err = strconv.Atoi(getEnvOrDefault("SPACEX_CACHE_SIZE", "1000"))
+	if err != nil {
+		panic(err)
+	}
+	launchpadTTL, err = getDurationFromEnv("SPACEX_LAUNCHPAD_TTL", "1h")
+	if err != nil {
+		panic(err)
+	}
+	launchesTTL, err = getDurationFromEnv("SPACEX_LAUNCHES_TTL", "5m")
+	if err != nil {
+		panic(err)
+	}
+	maxStale, err = getDurationFromEnv("SPACEX_CACHE_MAX_STALE", "30m")
+	if err != nil {
+		panic(err)
+	}
+	persistCache, err = strconv.ParseBool(getEnvOrDefault("SPACEX_CACHE_PERSIST", "false"))
+	if err != nil {
+		panic(err)
+	}
+
+	maxAttempts, err = strconv.Atoi(getEnvOrDefault("SPACEX_MAX_ATTEMPTS", "3"))
+	if err != nil {
+		panic(err)
+	}
+	retryBaseDelay, err = getDurationFromEnv("SPACEX_RETRY_BASE_DELAY", "200ms")
+	if err != nil {
+		panic(err)
+	}
+	retryMaxDelay, err = getDurationFromEnv("SPACEX_RETRY_MAX_DELAY", "5s")
+	if err != nil {
+		panic(err)
+	}
+	breakerThreshold, err = strconv.Atoi(getEnvOrDefault("SPACEX_BREAKER_THRESHOLD", "5"))
+	if err != nil {
+		panic(err)
+	}
+	breakerOpenFor, err = getDurationFromEnv("SPACEX_BREAKER_OPEN_FOR", "30s")
+	if err != nil {
+		panic(err)
+	}
+
+	useSchedule, err = strconv.ParseBool(getEnvOrDefault("SPACEX_SCHEDULE", "false"))
+	if err != nil {
+		panic(err)
+	}
+	scheduleMaxStale, err = getDurationFromEnv("SPACEX_SCHEDULE_MAX_STALE", "1h")
+	if err != nil {
+		panic(err)
+	}
+	syncInterval, err = getDurationFromEnv("SPACEX_SYNC_INTERVAL", "10m")
+	if err != nil {
+		panic(err)
+	}
+
 	cfg := Config{
 		ServerAddress:      getEnvOrDefault("SERVER_ADDRESS", ":5000"),
 		ServerWriteTimeout: serverWriteTimeout,
@@ -65,6 +219,34 @@ func NewConfig() *Config {
 		PgPasswd:           getEnvOrDefault("POSTGRES_PASSWORD", ""),
 		PgPoolMaxConn:      maxConns,
 		SpaceXUrl:          getEnvOrDefault("SPACEX_URL", "https://api.spacexdata.com/v4"),
+
+		SpaceXCacheSize:     spaceXCacheSize,
+		SpaceXLaunchpadTTL:  launchpadTTL,
+		SpaceXLaunchesTTL:   launchesTTL,
+		SpaceXCacheMaxStale: maxStale,
+		SpaceXCachePersist:  persistCache,
+
+		SpaceXMaxAttempts:      maxAttempts,
+		SpaceXRetryBaseDelay:   retryBaseDelay,
+		SpaceXRetryMaxDelay:    retryMaxDelay,
+		SpaceXBreakerThreshold: breakerThreshold,
+		SpaceXBreakerOpenFor:   breakerOpenFor,
+		SpaceXSchedule:         useSchedule,
+		SpaceXScheduleMaxStale: scheduleMaxStale,
+		SpaceXSyncInterval:     syncInterval,
+
+		FlightCapacity:              flightCapacity,
+		FlightCapacityByLaunchpad:   launchpadCapacity,
+		FlightCapacityByDestination: destCapacity,
+		HoldSweepInterval:           holdSweepInterval,
+		PaymentWindow:               paymentWindow,
+		IdempotencyPendingTTL:       idempotencyTTL,
+		BaseFare:                    baseFare,
+		BaseFareByDestination:       make(map[string]int64, len(destFares)),
+		CursorSecret:                getEnvOrDefault("CURSOR_SECRET", ""),
+	}
+	for k, v := range destFares {
+		cfg.BaseFareByDestination[k] = int64(v)
 	}
 	return &cfg
 }
@@ -81,3 +263,24 @@ func getDurationFromEnv(key, value string) (time.Duration, error) {
 	v := getEnvOrDefault(key, value)
 	return time.ParseDuration(v)
 }
+
+// getIntMapFromEnv parses values formatted as "key1=1,key2=2".
+func getIntMapFromEnv(key, value string) (map[string]int, error) {
+	ans := make(map[string]int)
+	v := getEnvOrDefault(key, value)
+	if v == "" {
+		return ans, nil
+	}
+	for _, pair := range strings.Split(v, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%s: invalid entry %q", key, pair)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		ans[strings.TrimSpace(kv[0])] = n
+	}
+	return ans, nil
+}
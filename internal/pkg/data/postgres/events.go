@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+// recordEventTx appends the event to the booking history within the transaction of the change,
+// the actor is taken from the context.
+func (o *Store) recordEventTx(ctx context.Context, tx pgx.Tx, e entity.BookingEvent) error {
+	q := `INSERT INTO booking_events(booking_id, kind, from_status, to_status, flight_id,
+			previous_flight_id, details, actor, created_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := tx.Exec(ctx, q, e.BookingID, e.Kind, nullString(e.FromStatus), e.ToStatus, e.FlightID,
+		e.PreviousFlightID, nullString(e.Details), entity.ActorFromContext(ctx), time.Now().UTC())
+	return err
+}
+
+func (o *Store) BookingEvents(ctx context.Context, bookingId string) ([]entity.BookingEvent, error) {
+	q := `SELECT id, booking_id, kind, COALESCE(from_status, ''), to_status, flight_id,
+			previous_flight_id, COALESCE(details, ''), actor, created_at
+		FROM booking_events WHERE booking_id = $1 ORDER BY id`
+	rows, err := o.db.Query(ctx, q, bookingId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []entity.BookingEvent
+	for rows.Next() {
+		var e entity.BookingEvent
+		err := rows.Scan(&e.ID, &e.BookingID, &e.Kind, &e.FromStatus, &e.ToStatus, &e.FlightID,
+			&e.PreviousFlightID, &e.Details, &e.Actor, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, e)
+	}
+	return items, rows.Err()
+}
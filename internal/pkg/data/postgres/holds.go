@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+func (o *Store) CreateHold(ctx context.Context, u entity.User, f entity.Flight, price int64, expiresAt time.Time) (entity.Booking, error) {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return entity.Booking{}, err
+	}
+	defer tx.Rollback(ctx)
+	expiresAt = expiresAt.UTC()
+	nb, err := o.createBookingTx(ctx, tx, entity.Booking{
+		User:      u,
+		Flight:    f,
+		Status:    entity.BookingStatusHeld,
+		Price:     price,
+		ExpiresAt: &expiresAt,
+	})
+	if err != nil {
+		return nb, err
+	}
+	return nb, tx.Commit(ctx)
+}
+
+func (o *Store) ExpireHolds(ctx context.Context, now time.Time) ([]entity.Booking, error) {
+	q := `SELECT id FROM bookings WHERE status = ANY($1) AND expires_at <= $2 ORDER BY expires_at`
+	statuses := []string{entity.BookingStatusHeld, entity.BookingStatusPendingPayment}
+	rows, err := o.db.Query(ctx, q, statuses, now)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var items []entity.Booking
+	for _, id := range ids {
+		b, err := o.expireHold(ctx, id, now)
+		if errors.Is(err, entity.ErrInvalidStatusTransition) {
+			// confirmed or cancelled meanwhile
+			continue
+		}
+		if err != nil {
+			return items, err
+		}
+		items = append(items, b)
+	}
+	return items, nil
+}
+
+// expireHold expires a single hold or unpaid booking in its own transaction, releasing the flight
+// when it was the last seat taken, the same way a cancellation does.
+func (o *Store) expireHold(ctx context.Context, id string, now time.Time) (entity.Booking, error) {
+	q := `UPDATE bookings SET status = $2 WHERE id = $1`
+
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return entity.Booking{}, err
+	}
+	defer tx.Rollback(ctx)
+	b, err := o.getBookingForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return b, err
+	}
+	if !entity.CanTransitionBooking(b.Status, entity.BookingStatusExpired) ||
+		b.ExpiresAt == nil || b.ExpiresAt.After(now) {
+		return b, entity.ErrInvalidStatusTransition
+	}
+	if _, err := tx.Exec(ctx, q, b.ID, entity.BookingStatusExpired); err != nil {
+		return b, err
+	}
+	err = o.recordEventTx(ctx, tx, entity.BookingEvent{
+		BookingID:  b.ID,
+		Kind:       entity.BookingEventExpired,
+		FromStatus: b.Status,
+		ToStatus:   entity.BookingStatusExpired,
+		FlightID:   b.Flight.ID,
+	})
+	if err != nil {
+		return b, err
+	}
+	b.Status = entity.BookingStatusExpired
+	b.Flight.Status, err = o.releaseFlightIfEmptyTx(ctx, tx, b.Flight.ID)
+	if err != nil {
+		return b, err
+	}
+	return b, tx.Commit(ctx)
+}
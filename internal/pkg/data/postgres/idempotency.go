@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+const (
+	// idempotencyKeyTTL is how long responses are replayed for.
+	idempotencyKeyTTL = 24 * time.Hour
+	// defaultPendingIdempotencyKeyTTL frees keys of requests that never completed, e.g. the server crashed.
+	// It must be longer than the slowest request, or a retry runs while the original is still in progress.
+	defaultPendingIdempotencyKeyTTL = 10 * time.Minute
+)
+
+func (o *Store) ClaimIdempotencyKey(ctx context.Context, key, requestHash string) (entity.IdempotencyRecord, bool, error) {
+	cq := `INSERT INTO idempotency_keys(key, request_hash, created_at) VALUES($1, $2, $3)
+		ON CONFLICT(key) DO UPDATE SET request_hash = EXCLUDED.request_hash, created_at = EXCLUDED.created_at,
+			status_code = NULL, response = NULL
+		WHERE idempotency_keys.created_at < $4
+			OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < $5)
+		RETURNING key`
+	sq := `SELECT key, request_hash, COALESCE(status_code, 0), response, created_at FROM idempotency_keys WHERE key = $1`
+
+	now := time.Now().UTC()
+	rec := entity.IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+	}
+	var claimedKey string
+	err := o.db.QueryRow(ctx, cq, key, requestHash, now,
+		now.Add(-idempotencyKeyTTL), now.Add(-o.pendingIdempotencyKeyTTL)).Scan(&claimedKey)
+	if err == nil {
+		return rec, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return rec, false, err
+	}
+	err = o.db.QueryRow(ctx, sq, key).Scan(&rec.Key, &rec.RequestHash, &rec.StatusCode, &rec.Body, &rec.CreatedAt)
+	return rec, false, err
+}
+
+func (o *Store) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, body []byte) error {
+	q := `UPDATE idempotency_keys SET status_code = $2, response = $3 WHERE key = $1`
+	_, err := o.db.Exec(ctx, q, key, statusCode, body)
+	return err
+}
+
+func (o *Store) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := o.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE key = $1`, key)
+	return err
+}
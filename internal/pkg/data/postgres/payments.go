@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+const selectPaymentQ = `SELECT id, booking_id, kind, amount, currency, provider_ref, created_at FROM payments`
+
+func scanPayment(row pgx.Row) (entity.Payment, error) {
+	var p entity.Payment
+	err := row.Scan(&p.ID, &p.BookingID, &p.Kind, &p.Amount, &p.Currency, &p.ProviderRef, &p.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return p, entity.ErrNotFound
+	}
+	return p, err
+}
+
+// ConfirmBooking turns a held or pending payment booking into an active one along with
+// its payment capture. A hold must not have expired, even if the sweeper did not get to it yet.
+func (o *Store) ConfirmBooking(ctx context.Context, id string, capture entity.Payment) (entity.Booking, error) {
+	q := `UPDATE bookings SET status = $2, expires_at = NULL WHERE id = $1`
+
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return entity.Booking{}, err
+	}
+	defer tx.Rollback(ctx)
+	b, err := o.getBookingForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return b, err
+	}
+	if !entity.CanTransitionBooking(b.Status, entity.BookingStatusActive) {
+		return b, entity.ErrInvalidStatusTransition
+	}
+	if b.ExpiresAt != nil && !b.ExpiresAt.After(time.Now()) {
+		return b, entity.ErrHoldExpired
+	}
+	capture.BookingID = b.ID
+	capture.Kind = entity.PaymentKindCapture
+	if _, err := o.createPaymentTx(ctx, tx, capture); err != nil {
+		return b, err
+	}
+	if _, err := tx.Exec(ctx, q, b.ID, entity.BookingStatusActive); err != nil {
+		return b, err
+	}
+	err = o.recordEventTx(ctx, tx, entity.BookingEvent{
+		BookingID:  b.ID,
+		Kind:       entity.BookingEventConfirmed,
+		FromStatus: b.Status,
+		ToStatus:   entity.BookingStatusActive,
+		FlightID:   b.Flight.ID,
+		Details:    "payment " + capture.ProviderRef,
+	})
+	if err != nil {
+		return b, err
+	}
+	b.Status = entity.BookingStatusActive
+	b.ExpiresAt = nil
+	return b, tx.Commit(ctx)
+}
+
+func (o *Store) CapturedPayment(ctx context.Context, bookingId string) (entity.Payment, error) {
+	q := selectPaymentQ + ` WHERE booking_id = $1 AND kind = $2`
+	return scanPayment(o.db.QueryRow(ctx, q, bookingId, entity.PaymentKindCapture))
+}
+
+func (o *Store) CreatePayment(ctx context.Context, p entity.Payment) (entity.Payment, error) {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return p, err
+	}
+	defer tx.Rollback(ctx)
+	p, err = o.createPaymentTx(ctx, tx, p)
+	if err != nil {
+		return p, err
+	}
+	return p, tx.Commit(ctx)
+}
+
+func (o *Store) createPaymentTx(ctx context.Context, tx pgx.Tx, p entity.Payment) (entity.Payment, error) {
+	q := `INSERT INTO payments(id, booking_id, kind, amount, currency, provider_ref, created_at)
+VALUES($1, $2, $3, $4, $5, $6, $7)`
+	p.ID = uuid.New()
+	p.CreatedAt = time.Now().UTC()
+	_, err := tx.Exec(ctx, q, p.ID, p.BookingID, p.Kind, p.Amount, p.Currency, p.ProviderRef, p.CreatedAt)
+	if isUniqueViolation(err) {
+		return p, entity.ErrAlreadyExists
+	}
+	return p, err
+}
+
+func (o *Store) PendingRefunds(ctx context.Context) ([]entity.Payment, error) {
+	q := selectPaymentQ + ` WHERE kind = $1 ORDER BY created_at`
+	rows, err := o.db.Query(ctx, q, entity.PaymentKindRefundPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ans := make([]entity.Payment, 0)
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		ans = append(ans, p)
+	}
+	return ans, rows.Err()
+}
+
+func (o *Store) ResolvePendingRefund(ctx context.Context, id uuid.UUID, providerRef string) (entity.Payment, error) {
+	q := `DELETE FROM payments WHERE id = $1 AND kind = $2
+		RETURNING id, booking_id, kind, amount, currency, provider_ref, created_at`
+
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return entity.Payment{}, err
+	}
+	defer tx.Rollback(ctx)
+	pending, err := scanPayment(tx.QueryRow(ctx, q, id, entity.PaymentKindRefundPending))
+	if err != nil {
+		return pending, err
+	}
+	refund, err := o.createPaymentTx(ctx, tx, entity.Payment{
+		BookingID:   pending.BookingID,
+		Kind:        entity.PaymentKindRefund,
+		Amount:      pending.Amount,
+		Currency:    pending.Currency,
+		ProviderRef: providerRef,
+	})
+	if err != nil {
+		return refund, err
+	}
+	return refund, tx.Commit(ctx)
+}
@@ -2,49 +2,76 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 
 	"spacetrouble/internal/pkg/entity"
 )
 
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const uniqueViolationCode = "23505"
+
 type Store struct {
 	db *pgxpool.Pool
+
+	pendingIdempotencyKeyTTL time.Duration
+}
+
+type StoreOption func(*Store)
+
+// WithPendingIdempotencyKeyTTL frees the Idempotency-Key of a request still in progress after ttl.
+func WithPendingIdempotencyKeyTTL(ttl time.Duration) StoreOption {
+	return func(o *Store) {
+		if ttl > 0 {
+			o.pendingIdempotencyKeyTTL = ttl
+		}
+	}
 }
 
-func NewStore(db *pgxpool.Pool) *Store {
+func NewStore(db *pgxpool.Pool, opts ...StoreOption) *Store {
 	ans := Store{
-		db: db,
+		db:                       db,
+		pendingIdempotencyKeyTTL: defaultPendingIdempotencyKeyTTL,
+	}
+	for _, opt := range opts {
+		opt(&ans)
 	}
 	return &ans
 }
 
-func (o *Store) AllBookingsPaginated(ctx context.Context, afterTime time.Time, afterUuid string, limit int) ([]entity.Booking, error) {
-	q := `SELECT 
-			B.id, B.status, B.created_at,
+const selectBookingQ = `SELECT 
+			B.id, B.status, B.created_at, B.cancelled_at, COALESCE(B.cancel_reason, ''), B.expires_at, B.price,
 			U.id, U.first_name, U.last_name, U.gender, U.birthday,
-			F.id, F.launchpad_id, F.launch_date,
+			F.id, F.launchpad_id, F.launch_date, F.status, F.capacity,
 			D.id, D.name
 		FROM bookings B 
 		JOIN users U ON U.id = B.user_id
 		JOIN flights F ON F.id = B.flight_id
 		JOIN destinations D ON D.id = F.destination_id
 		`
-	var args []interface{}
-	if !afterTime.IsZero() && afterUuid != "" {
-		q += " WHERE B.created_at > $1 AND B.id > $2"
-		args = append(args, afterTime, afterUuid)
-	}
 
-	q += " ORDER BY B.created_at, B.id"
-	q += fmt.Sprintf(" LIMIT $%d", len(args)+1)
-	args = append(args, limit)
+func scanBooking(row pgx.Row) (entity.Booking, error) {
+	var item entity.Booking
+	err := row.Scan(
+		&item.ID, &item.Status, &item.CreatedAt, &item.CancelledAt, &item.CancelReason, &item.ExpiresAt, &item.Price,
+		&item.User.ID, &item.User.FirstName, &item.User.LastName,
+		&item.User.Gender, &item.User.Birthday,
+		&item.Flight.ID, &item.Flight.LaunchpadID, &item.Flight.Date, &item.Flight.Status, &item.Flight.Capacity,
+		&item.Flight.Destination.ID, &item.Flight.Destination.Name,
+	)
+	return item, err
+}
 
+func (o *Store) AllBookingsPaginated(ctx context.Context, filter entity.BookingFilter) ([]entity.Booking, error) {
+	q, args := buildSelectBookingsQ(filter)
 	rows, err := o.db.Query(ctx, q, args...)
 	if err != nil {
 		return nil, err
@@ -52,14 +79,7 @@ func (o *Store) AllBookingsPaginated(ctx context.Context, afterTime time.Time, a
 	defer rows.Close()
 	var items []entity.Booking
 	for rows.Next() {
-		var item entity.Booking
-		err := rows.Scan(
-			&item.ID, &item.Status, &item.CreatedAt,
-			&item.User.ID, &item.User.FirstName, &item.User.LastName,
-			&item.User.Gender, &item.User.Birthday,
-			&item.Flight.ID, &item.Flight.LaunchpadID, &item.Flight.Date,
-			&item.Flight.Destination.ID, &item.Flight.Destination.Name,
-		)
+		item, err := scanBooking(rows)
 		if err != nil {
 			return items, err
 		}
@@ -68,14 +88,337 @@ func (o *Store) AllBookingsPaginated(ctx context.Context, afterTime time.Time, a
 	return items, rows.Err()
 }
 
+// StreamBookings calls fn with every booking matching the filter, in its sort order and
+// regardless of its page. Rows are scanned as they are read from the connection so that
+// the result set is never held in memory, the iteration stops at the first error of fn.
+func (o *Store) StreamBookings(ctx context.Context, filter entity.BookingFilter, fn func(entity.Booking) error) error {
+	filter.AfterValue, filter.AfterID, filter.Limit = time.Time{}, "", 0
+	q, args := buildSelectBookingsQ(filter)
+	rows, err := o.db.Query(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		item, err := scanBooking(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// bookingSortColumns maps the sort orders of entity.BookingFilter to their column,
+// the booking id breaks the ties so that keyset pagination is stable.
+var bookingSortColumns = map[string]string{
+	"":                           "B.created_at",
+	entity.BookingSortCreatedAt:  "B.created_at",
+	entity.BookingSortLaunchDate: "F.launch_date",
+}
+
+// CountBookings counts the bookings matching the filter regardless of its page,
+// exactly or from the estimate of the query planner, which is cheaper on large tables.
+func (o *Store) CountBookings(ctx context.Context, filter entity.BookingFilter, exact bool) (int64, error) {
+	filter.AfterValue, filter.AfterID, filter.Limit = time.Time{}, "", 0
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	from := `FROM bookings B
+		JOIN users U ON U.id = B.user_id
+		JOIN flights F ON F.id = B.flight_id`
+	if whereConds := bookingFilterConds(filter, arg); len(whereConds) > 0 {
+		from += " WHERE " + strings.Join(whereConds, " AND ")
+	}
+	var n int64
+	if exact {
+		err := o.db.QueryRow(ctx, "SELECT count(*) "+from, args...).Scan(&n)
+		return n, err
+	}
+	var plan []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		}
+	}
+	var raw []byte
+	if err := o.db.QueryRow(ctx, "EXPLAIN (FORMAT JSON) SELECT B.id "+from, args...).Scan(&raw); err != nil {
+		return n, err
+	}
+	if err := json.Unmarshal(raw, &plan); err != nil {
+		return n, err
+	}
+	if len(plan) > 0 {
+		n = int64(plan[0].Plan.Rows)
+	}
+	return n, nil
+}
+
+func buildSelectBookingsQ(filter entity.BookingFilter) (string, []interface{}) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	q := selectBookingQ
+	whereConds := bookingFilterConds(filter, arg)
+	col := bookingSortColumns[filter.SortBy]
+	cmp, dir := ">", ""
+	if filter.Desc {
+		cmp, dir = "<", " DESC"
+	}
+	// a row value comparison, the booking id only counts when the sort keys are equal
+	if !filter.AfterValue.IsZero() && filter.AfterID != "" {
+		whereConds = append(whereConds, fmt.Sprintf("(%s, B.id) %s (%s, %s)",
+			col, cmp, arg(filter.AfterValue), arg(filter.AfterID)))
+	}
+	if len(whereConds) > 0 {
+		q += " WHERE " + strings.Join(whereConds, " AND ")
+	}
+	q += fmt.Sprintf(" ORDER BY %s%s, B.id%s", col, dir, dir)
+	if filter.Limit > 0 {
+		q += " LIMIT " + arg(filter.Limit)
+	}
+	return q, args
+}
+
+// bookingFilterConds returns the conditions of the filter but its page, arg binds the values.
+func bookingFilterConds(filter entity.BookingFilter, arg func(v interface{}) string) []string {
+	var whereConds []string
+	if filter.UserID != "" {
+		whereConds = append(whereConds, "U.id = "+arg(filter.UserID))
+	}
+	if filter.Status != "" {
+		whereConds = append(whereConds, "B.status = "+arg(filter.Status))
+	}
+	if filter.DestinationID != "" {
+		whereConds = append(whereConds, "F.destination_id = "+arg(filter.DestinationID))
+	}
+	if filter.LaunchpadID != "" {
+		whereConds = append(whereConds, "F.launchpad_id = "+arg(filter.LaunchpadID))
+	}
+	if !filter.LaunchFrom.IsZero() {
+		whereConds = append(whereConds, "F.launch_date >= "+arg(filter.LaunchFrom))
+	}
+	if !filter.LaunchTo.IsZero() {
+		whereConds = append(whereConds, "F.launch_date <= "+arg(filter.LaunchTo))
+	}
+	if filter.PassengerName != "" {
+		whereConds = append(whereConds, fmt.Sprintf(`U.first_name || ' ' || U.last_name ILIKE '%%' || %s || '%%'`,
+			arg(escapeLike(filter.PassengerName))))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		whereConds = append(whereConds, "B.created_at >= "+arg(filter.CreatedFrom))
+	}
+	if !filter.CreatedTo.IsZero() {
+		whereConds = append(whereConds, "B.created_at < "+arg(filter.CreatedTo))
+	}
+	return whereConds
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern so that s is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (o *Store) GetBookingById(ctx context.Context, id string) (entity.Booking, error) {
+	item, err := scanBooking(o.db.QueryRow(ctx, selectBookingQ+" WHERE B.id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return item, entity.ErrNotFound
+	}
+	return item, err
+}
+
+// getBookingForUpdateTx fetches a booking locking its row until the end of the transaction.
+func (o *Store) getBookingForUpdateTx(ctx context.Context, tx pgx.Tx, id string) (entity.Booking, error) {
+	item, err := scanBooking(tx.QueryRow(ctx, selectBookingQ+" WHERE B.id = $1 FOR UPDATE OF B", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return item, entity.ErrNotFound
+	}
+	return item, err
+}
+
+func (o *Store) CancelBooking(ctx context.Context, id string, reason string) (entity.Booking, error) {
+	q := `UPDATE bookings SET status = $2, cancelled_at = $3, cancel_reason = $4, expires_at = NULL WHERE id = $1`
+
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return entity.Booking{}, err
+	}
+	defer tx.Rollback(ctx)
+	b, err := o.getBookingForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return b, err
+	}
+	if !entity.CanTransitionBooking(b.Status, entity.BookingStatusCancelled) {
+		return b, entity.ErrInvalidStatusTransition
+	}
+	now := time.Now().UTC()
+	from := b.Status
+	b.Status = entity.BookingStatusCancelled
+	b.CancelledAt = &now
+	b.CancelReason = reason
+	b.ExpiresAt = nil
+	if _, err := tx.Exec(ctx, q, b.ID, b.Status, now, nullString(reason)); err != nil {
+		return b, err
+	}
+	err = o.recordEventTx(ctx, tx, entity.BookingEvent{
+		BookingID:  b.ID,
+		Kind:       entity.BookingEventCancelled,
+		FromStatus: from,
+		ToStatus:   b.Status,
+		FlightID:   b.Flight.ID,
+		Details:    reason,
+	})
+	if err != nil {
+		return b, err
+	}
+	b.Flight.Status, err = o.releaseFlightIfEmptyTx(ctx, tx, b.Flight.ID)
+	if err != nil {
+		return b, err
+	}
+	return b, tx.Commit(ctx)
+}
+
+// RebookBooking moves the booking to the flight, creating the flight when it has no ID yet.
+// The booking keeps its ID, and its previous flight is released when it was the last seat taken.
+func (o *Store) RebookBooking(ctx context.Context, id string, f entity.Flight) (entity.Booking, error) {
+	q := `UPDATE bookings SET flight_id = $2 WHERE id = $1`
+
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return entity.Booking{}, err
+	}
+	defer tx.Rollback(ctx)
+	b, err := o.getBookingForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return b, err
+	}
+	if !entity.IsOccupyingBookingStatus(b.Status) {
+		return b, entity.ErrInvalidStatusTransition
+	}
+	previous := b.Flight
+	if !f.IsIDEmpty() {
+		// lock both flights in the same order whatever the direction so that rebookings cannot deadlock
+		first, second := previous.ID, f.ID
+		if second.String() < first.String() {
+			first, second = second, first
+		}
+		for _, fid := range []uuid.UUID{first, second} {
+			if _, _, err := o.lockFlightTx(ctx, tx, fid); err != nil {
+				return b, err
+			}
+		}
+	} else {
+		// the new flight may take the launchpad or the week of the previous one,
+		// released first when the booking is its last seat
+		if _, err := o.releaseFlightTx(ctx, tx, previous.ID, 1); err != nil {
+			return b, err
+		}
+	}
+	b.Flight, err = o.takeSeatsTx(ctx, tx, f, 1)
+	if err != nil {
+		return b, err
+	}
+	if _, err := tx.Exec(ctx, q, b.ID, b.Flight.ID); err != nil {
+		if isUniqueViolation(err) {
+			return b, entity.ErrAlreadyBooked
+		}
+		return b, err
+	}
+	err = o.recordEventTx(ctx, tx, entity.BookingEvent{
+		BookingID:        b.ID,
+		Kind:             entity.BookingEventRebooked,
+		FromStatus:       b.Status,
+		ToStatus:         b.Status,
+		FlightID:         b.Flight.ID,
+		PreviousFlightID: &previous.ID,
+	})
+	if err != nil {
+		return b, err
+	}
+	if _, err := o.releaseFlightIfEmptyTx(ctx, tx, previous.ID); err != nil {
+		return b, err
+	}
+	return b, tx.Commit(ctx)
+}
+
+// releaseFlightIfEmptyTx cancels the flight when it has no seat taken anymore
+// so that its launchpad and date can be used for other destinations.
+// It returns the resulting status of the flight.
+func (o *Store) releaseFlightIfEmptyTx(ctx context.Context, tx pgx.Tx, flightId uuid.UUID) (string, error) {
+	return o.releaseFlightTx(ctx, tx, flightId, 0)
+}
+
+// releaseFlightTx cancels the flight when it has no seat taken but the leaving ones.
+func (o *Store) releaseFlightTx(ctx context.Context, tx pgx.Tx, flightId uuid.UUID, leaving int) (string, error) {
+	status, _, err := o.lockFlightTx(ctx, tx, flightId)
+	if err != nil || status != entity.FlightStatusScheduled {
+		return status, err
+	}
+	cnt, err := o.countSeatsTakenTx(ctx, tx, flightId)
+	if err != nil {
+		return status, err
+	}
+	if cnt > leaving {
+		return status, nil
+	}
+	uq := `UPDATE flights SET status = $2 WHERE id = $1`
+	if _, err := tx.Exec(ctx, uq, flightId, entity.FlightStatusCancelled); err != nil {
+		return status, err
+	}
+	return entity.FlightStatusCancelled, nil
+}
+
+// lockFlightTx locks the flight row until the end of the transaction, serializing
+// bookings and cancellations on the flight, and returns its status and capacity.
+func (o *Store) lockFlightTx(ctx context.Context, tx pgx.Tx, flightId uuid.UUID) (string, int, error) {
+	var (
+		status   string
+		capacity int
+	)
+	q := `SELECT status, capacity FROM flights WHERE id = $1 FOR UPDATE`
+	err := tx.QueryRow(ctx, q, flightId).Scan(&status, &capacity)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return status, capacity, entity.ErrNotFound
+	}
+	return status, capacity, err
+}
+
+// countSeatsTakenTx counts the active and held bookings of the flight.
+func (o *Store) countSeatsTakenTx(ctx context.Context, tx pgx.Tx, flightId uuid.UUID) (int, error) {
+	var cnt int
+	q := `SELECT count(1) FROM bookings WHERE flight_id = $1 AND status = ANY($2)`
+	err := tx.QueryRow(ctx, q, flightId, entity.OccupyingBookingStatuses).Scan(&cnt)
+	return cnt, err
+}
+
+// CountSeatsTaken counts the active and held bookings of the flight.
+func (o *Store) CountSeatsTaken(ctx context.Context, flightId string) (int, error) {
+	var cnt int
+	q := `SELECT count(1) FROM bookings WHERE flight_id = $1 AND status = ANY($2)`
+	err := o.db.QueryRow(ctx, q, flightId, entity.OccupyingBookingStatuses).Scan(&cnt)
+	return cnt, err
+}
+
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
 func (o *Store) GetLaunchPadWeekAvailability(ctx context.Context, launchpadId, destinationId string,
-	t time.Time) (bool, error) {
+	t time.Time, except uuid.UUID) (bool, error) {
 	tx, err := o.db.Begin(ctx)
 	if err != nil {
 		return false, err
 	}
 	defer tx.Rollback(ctx)
-	ans, err := o.getLaunchPadWeekAvailabiltyTx(ctx, tx, launchpadId, destinationId, t)
+	ans, err := o.getLaunchPadWeekAvailabiltyTx(ctx, tx, launchpadId, destinationId, t, except)
 	if err != nil {
 		return ans, err
 	}
@@ -83,15 +426,16 @@ func (o *Store) GetLaunchPadWeekAvailability(ctx context.Context, launchpadId, d
 }
 
 func (o *Store) getLaunchPadWeekAvailabiltyTx(ctx context.Context, tx pgx.Tx,
-	launchpadId, destinationId string, t time.Time) (bool, error) {
+	launchpadId, destinationId string, t time.Time, except uuid.UUID) (bool, error) {
 	var ans bool
-	err := tx.QueryRow(ctx, `SELECT launch_in_same_week($1, $2, $3)`, launchpadId, destinationId, t).Scan(&ans)
+	err := tx.QueryRow(ctx, `SELECT launch_in_same_week_except($1, $2, $3, $4)`,
+		except, launchpadId, destinationId, t).Scan(&ans)
 
 	return ans, err
 }
 
 func (o *Store) GetAllDestinations(ctx context.Context) ([]entity.Destination, error) {
-	q := `SELECT id, name FROM destinations`
+	q := `SELECT id, name FROM destinations ORDER BY name`
 	rows, err := o.db.Query(ctx, q)
 	if err != nil {
 		return nil, err
@@ -115,6 +459,9 @@ func (o *Store) CreateDestination(ctx context.Context, name string) (entity.Dest
 		Name: name,
 	}
 	_, err := o.db.Exec(ctx, q, dst.ID, dst.Name)
+	if isUniqueViolation(err) {
+		return dst, entity.ErrAlreadyExists
+	}
 	return dst, err
 }
 
@@ -122,47 +469,243 @@ func (o *Store) GetDestinationById(ctx context.Context, id string) (entity.Desti
 	q := `SELECT id, name FROM destinations WHERE id = $1`
 	var dest entity.Destination
 	if err := o.db.QueryRow(ctx, q, id).Scan(&dest.ID, &dest.Name); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dest, entity.ErrNotFound
+		}
 		return dest, err
 	}
 	return dest, nil
 
 }
 
-func (o *Store) CreateBooking(ctx context.Context, u entity.User, f entity.Flight) (entity.Booking, error) {
-	uq := `INSERT INTO users(id, first_name, last_name, gender, birthday)
-			VALUES($1, $2, $3, $4, $5) ON CONFLICT(id) DO NOTHING`
-	fq := `INSERT INTO flights(id, launchpad_id, destination_id, launch_date)
-VALUES($1, $2, $3, $4)`
-	bq := `INSERT INTO bookings(id, user_id, flight_id, status, created_at) VALUES($1, $2, $3, $4, $5)`
+func (o *Store) RenameDestination(ctx context.Context, id string, name string) (entity.Destination, error) {
+	q := `UPDATE destinations SET name = $2 WHERE id = $1 RETURNING id, name`
+	var dest entity.Destination
+	if err := o.db.QueryRow(ctx, q, id, name).Scan(&dest.ID, &dest.Name); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dest, entity.ErrNotFound
+		}
+		if isUniqueViolation(err) {
+			return dest, entity.ErrAlreadyExists
+		}
+		return dest, err
+	}
+	return dest, nil
+}
 
-	// TODO maybe check for business rules violations within the transaction
-	// Now will return a constraint violation error.
+// DeleteDestination removes a destination as long as no flight references it.
+// Flights are deleted in cascade so we must check first, holding a lock
+// on the destination so that no flight gets created meanwhile.
+func (o *Store) DeleteDestination(ctx context.Context, id string) error {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	var found uuid.UUID
+	if err := tx.QueryRow(ctx, `SELECT id FROM destinations WHERE id = $1 FOR UPDATE`, id).Scan(&found); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.ErrNotFound
+		}
+		return err
+	}
+	// the waitlist would be deleted in cascade
+	q := `SELECT EXISTS(SELECT 1 FROM flights WHERE destination_id = $1)
+		OR EXISTS(SELECT 1 FROM waitlist WHERE destination_id = $1 AND status = $2)`
+	var inUse bool
+	if err := tx.QueryRow(ctx, q, id, entity.WaitlistStatusWaiting).Scan(&inUse); err != nil {
+		return err
+	}
+	if inUse {
+		return entity.ErrInUse
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM destinations WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
+
+func (o *Store) CreateBooking(ctx context.Context, u entity.User, f entity.Flight, price int64, payBy *time.Time) (entity.Booking, error) {
 	tx, err := o.db.Begin(ctx)
 	if err != nil {
 		return entity.Booking{}, err
 	}
+	defer tx.Rollback(ctx)
+	nb, err := o.createBookingTx(ctx, tx, newBooking(u, f, price, payBy))
+	if err != nil {
+		return nb, err
+	}
+	return nb, tx.Commit(ctx)
+}
+
+// newBooking is a booking pending payment until payBy, or active without payBy.
+func newBooking(u entity.User, f entity.Flight, price int64, payBy *time.Time) entity.Booking {
 	nb := entity.Booking{
-		ID:        uuid.New(),
-		User:      u,
-		Flight:    f,
-		Status:    entity.BookingStatusActive,
-		CreatedAt: time.Now().UTC(),
+		User:   u,
+		Flight: f,
+		Status: entity.BookingStatusActive,
+		Price:  price,
+	}
+	if payBy != nil {
+		t := payBy.UTC()
+		nb.Status, nb.ExpiresAt = entity.BookingStatusPendingPayment, &t
+	}
+	return nb
+}
+
+// CreateGroupBooking books all the passengers on the flight in a single transaction, all or nothing.
+// prices[i] is the price of users[i].
+func (o *Store) CreateGroupBooking(ctx context.Context, users []entity.User, prices []int64, f entity.Flight,
+	payBy time.Time) ([]entity.Booking, error) {
+	if len(prices) != len(users) {
+		return nil, errors.New("one price per passenger is expected")
+	}
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return nil, err
 	}
 	defer tx.Rollback(ctx)
+	f, err = o.takeSeatsTx(ctx, tx, f, len(users))
+	if err != nil {
+		return nil, err
+	}
+	payBy = payBy.UTC()
+	items := make([]entity.Booking, 0, len(users))
+	for i, u := range users {
+		nb, err := o.insertBookingTx(ctx, tx, entity.Booking{
+			User:      u,
+			Flight:    f,
+			Status:    entity.BookingStatusPendingPayment,
+			Price:     prices[i],
+			ExpiresAt: &payBy,
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, nb)
+	}
+	return items, tx.Commit(ctx)
+}
+
+// createBookingTx books a seat on the flight of nb with its status, price and expiry.
+func (o *Store) createBookingTx(ctx context.Context, tx pgx.Tx, nb entity.Booking) (entity.Booking, error) {
+	var err error
+	nb.Flight, err = o.takeSeatsTx(ctx, tx, nb.Flight, 1)
+	if err != nil {
+		return nb, err
+	}
+	return o.insertBookingTx(ctx, tx, nb)
+}
+
+// takeSeatsTx makes sure the flight has the seats left, creating the flight when it has no ID yet.
+// It returns the flight as stored.
+func (o *Store) takeSeatsTx(ctx context.Context, tx pgx.Tx, f entity.Flight, seats int) (entity.Flight, error) {
+	fq := `INSERT INTO flights(id, launchpad_id, destination_id, launch_date, status, capacity)
+VALUES($1, $2, $3, $4, $5, $6)`
+
+	// TODO maybe check the remaining business rules within the transaction
+	// Now will return a constraint violation error.
 	if f.IsIDEmpty() {
+		if seats > f.Capacity {
+			return f, entity.ErrFlightFull
+		}
 		f.ID = uuid.New()
-		nb.Flight.ID = f.ID
-		if _, err := tx.Exec(ctx, fq, f.ID, f.LaunchpadID, f.Destination.ID, f.Date); err != nil {
-			return nb, err
+		f.Status = entity.FlightStatusScheduled
+		if _, err := tx.Exec(ctx, fq, f.ID, f.LaunchpadID, f.Destination.ID, f.Date, f.Status, f.Capacity); err != nil {
+			return f, err
 		}
+		return f, nil
+	}
+	// the flight may have been cancelled or filled up since it was selected
+	status, capacity, err := o.lockFlightTx(ctx, tx, f.ID)
+	if err != nil {
+		return f, err
+	}
+	if status != entity.FlightStatusScheduled {
+		return f, entity.ErrFlightNotScheduled
+	}
+	taken, err := o.countSeatsTakenTx(ctx, tx, f.ID)
+	if err != nil {
+		return f, err
 	}
-	if _, err := tx.Exec(ctx, uq, u.ID, u.FirstName, u.LastName, u.Gender, u.Birthday); err != nil {
+	if taken+seats > capacity {
+		return f, entity.ErrFlightFull
+	}
+	f.Capacity = capacity
+	return f, nil
+}
+
+// insertBookingTx inserts nb on its already checked flight, resolving its passenger.
+func (o *Store) insertBookingTx(ctx context.Context, tx pgx.Tx, nb entity.Booking) (entity.Booking, error) {
+	bq := `INSERT INTO bookings(id, user_id, flight_id, status, created_at, expires_at, price)
+VALUES($1, $2, $3, $4, $5, $6, $7)`
+
+	nb.ID = uuid.New()
+	nb.CreatedAt = time.Now().UTC()
+	var err error
+	nb.User, err = o.resolveUserTx(ctx, tx, nb.User)
+	if err != nil {
 		return nb, err
 	}
-	if _, err := tx.Exec(ctx, bq, nb.ID, nb.User.ID, nb.Flight.ID, nb.Status, nb.CreatedAt); err != nil {
+	if _, err := tx.Exec(ctx, bq, nb.ID, nb.User.ID, nb.Flight.ID, nb.Status, nb.CreatedAt, nb.ExpiresAt, nb.Price); err != nil {
+		if isUniqueViolation(err) {
+			return nb, entity.ErrAlreadyBooked
+		}
 		return nb, err
 	}
-	return nb, tx.Commit(ctx)
+	err = o.recordEventTx(ctx, tx, entity.BookingEvent{
+		BookingID: nb.ID,
+		Kind:      entity.BookingEventCreated,
+		ToStatus:  nb.Status,
+		FlightID:  nb.Flight.ID,
+	})
+	return nb, err
+}
+
+func (o *Store) GetUserById(ctx context.Context, id string) (entity.User, error) {
+	return scanUser(o.db.QueryRow(ctx, selectUserQ+" WHERE id = $1", id))
+}
+
+const selectUserQ = `SELECT id, first_name, last_name, gender, birthday FROM users`
+
+func scanUser(row pgx.Row) (entity.User, error) {
+	var u entity.User
+	err := row.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Gender, &u.Birthday)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return u, entity.ErrNotFound
+	}
+	return u, err
+}
+
+// userIdentityCond matches the passenger of the first name, last name, gender and birthday
+// bound at $1 to $4, the names ignoring case.
+const userIdentityCond = `lower(first_name) = lower($1) AND lower(last_name) = lower($2) AND gender = $3 AND birthday = $4`
+
+// resolveUserTx returns the stored passenger. Without an ID the passenger is matched
+// on name, gender and birthday, and registered when it is the first time we see them.
+func (o *Store) resolveUserTx(ctx context.Context, tx pgx.Tx, u entity.User) (entity.User, error) {
+	if u.ID != uuid.Nil {
+		return scanUser(tx.QueryRow(ctx, selectUserQ+" WHERE id = $1", u.ID))
+	}
+	// serializes concurrent bookings of the same passenger so that it is registered once
+	lq := `SELECT pg_advisory_xact_lock(hashtext(lower($1) || '|' || lower($2) || '|' || $3 || '|' || $4::text))`
+	if _, err := tx.Exec(ctx, lq, u.FirstName, u.LastName, u.Gender, u.Birthday.Format("2006-01-02")); err != nil {
+		return u, err
+	}
+	mq := selectUserQ + " WHERE " + userIdentityCond + " ORDER BY id LIMIT 1"
+	found, err := scanUser(tx.QueryRow(ctx, mq, u.FirstName, u.LastName, u.Gender, u.Birthday))
+	if err == nil || !errors.Is(err, entity.ErrNotFound) {
+		return found, err
+	}
+	iq := `INSERT INTO users(id, first_name, last_name, gender, birthday) VALUES($1, $2, $3, $4, $5)`
+	u.ID = uuid.New()
+	_, err = tx.Exec(ctx, iq, u.ID, u.FirstName, u.LastName, u.Gender, u.Birthday)
+	return u, err
 }
 
 func (o *Store) SelectFlights(ctx context.Context, filters map[string]interface{}) ([]entity.Flight, error) {
@@ -180,7 +723,7 @@ func (o *Store) SelectFlights(ctx context.Context, filters map[string]interface{
 
 func (o *Store) buildSelectFlightQ(filters map[string]interface{}) (string, []interface{}) {
 	q := `SELECT 
-			F.id, F.launchpad_id, F.launch_date,
+			F.id, F.launchpad_id, F.launch_date, F.status, F.capacity,
 			D.id as destination_id, D.name as destination_name
 			FROM flights F
 			JOIN destinations D ON D.id = F.destination_id`
@@ -197,7 +740,12 @@ func (o *Store) buildSelectFlightQ(filters map[string]interface{}) (string, []in
 		}
 	}
 	if hasBookingStatus {
-		whereConds = append(whereConds, fmt.Sprintf("B.status=$%d", len(args)+1))
+		// a list of statuses matches any of them
+		op := "="
+		if _, ok := bookingStatus.([]string); ok {
+			op = "= ANY"
+		}
+		whereConds = append(whereConds, fmt.Sprintf("B.status %s($%d)", op, len(args)+1))
 		args = append(args, bookingStatus)
 	}
 	if len(whereConds) > 0 {
@@ -220,7 +768,7 @@ func (o *Store) selectFlightsTx(ctx context.Context, tx pgx.Tx, filters map[stri
 	for rows.Next() {
 		var flight entity.Flight
 		err := rows.Scan(
-			&flight.ID, &flight.LaunchpadID, &flight.Date,
+			&flight.ID, &flight.LaunchpadID, &flight.Date, &flight.Status, &flight.Capacity,
 			&flight.Destination.ID, &flight.Destination.Name,
 		)
 		if err != nil {
@@ -230,3 +778,76 @@ func (o *Store) selectFlightsTx(ctx context.Context, tx pgx.Tx, filters map[stri
 	}
 	return items, rows.Err()
 }
+
+func (o *Store) SearchFlights(ctx context.Context, filter entity.FlightFilter) ([]entity.FlightSummary, error) {
+	q, args := o.buildSearchFlightsQ(filter)
+	rows, err := o.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []entity.FlightSummary
+	for rows.Next() {
+		var item entity.FlightSummary
+		err := rows.Scan(
+			&item.Flight.ID, &item.Flight.LaunchpadID, &item.Flight.Date, &item.Flight.Status, &item.Flight.Capacity,
+			&item.Flight.Destination.ID, &item.Flight.Destination.Name,
+			&item.Passengers,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (o *Store) buildSearchFlightsQ(filter entity.FlightFilter) (string, []interface{}) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	q := `SELECT 
+			F.id, F.launchpad_id, F.launch_date, F.status, F.capacity,
+			D.id, D.name,
+			count(B.id) FILTER (WHERE B.status = ANY(` + arg(entity.OccupyingBookingStatuses) + `))
+			FROM flights F
+			JOIN destinations D ON D.id = F.destination_id
+			LEFT JOIN bookings B ON B.flight_id = F.id`
+	var whereConds []string
+	if filter.LaunchpadID != "" {
+		whereConds = append(whereConds, "F.launchpad_id = "+arg(filter.LaunchpadID))
+	}
+	if filter.DestinationID != "" {
+		whereConds = append(whereConds, "F.destination_id = "+arg(filter.DestinationID))
+	}
+	if filter.Status != "" {
+		whereConds = append(whereConds, "F.status = "+arg(filter.Status))
+	}
+	if !filter.From.IsZero() {
+		whereConds = append(whereConds, "F.launch_date >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		whereConds = append(whereConds, "F.launch_date <= "+arg(filter.To))
+	}
+	if !filter.AfterDate.IsZero() && filter.AfterID != "" {
+		whereConds = append(whereConds, fmt.Sprintf("(F.launch_date, F.id) > (%s, %s)", arg(filter.AfterDate), arg(filter.AfterID)))
+	}
+	if len(whereConds) > 0 {
+		q += " WHERE " + strings.Join(whereConds, " AND ")
+	}
+	q += " GROUP BY F.id, D.id"
+	if filter.HasActiveBookings != nil {
+		op := "="
+		if *filter.HasActiveBookings {
+			op = ">"
+		}
+		q += fmt.Sprintf(" HAVING count(B.id) FILTER (WHERE B.status = %s) %s 0", arg(entity.BookingStatusActive), op)
+	}
+	q += " ORDER BY F.launch_date, F.id"
+	if filter.Limit > 0 {
+		q += " LIMIT " + arg(filter.Limit)
+	}
+	return q, args
+}
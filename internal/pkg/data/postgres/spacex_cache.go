@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+func (o *Store) GetSpaceXCacheEntry(ctx context.Context, key string) ([]byte, time.Time, error) {
+	var value []byte
+	var storedAt time.Time
+	err := o.db.QueryRow(ctx, `SELECT value, stored_at FROM spacex_cache WHERE key = $1`, key).Scan(&value, &storedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storedAt, entity.ErrNotFound
+	}
+	return value, storedAt, err
+}
+
+// PutSpaceXCacheEntry stores the entry unless a more recent one was stored meanwhile.
+func (o *Store) PutSpaceXCacheEntry(ctx context.Context, key string, value []byte, storedAt time.Time) error {
+	q := `INSERT INTO spacex_cache(key, value, stored_at) VALUES($1, $2, $3)
+		ON CONFLICT(key) DO UPDATE SET value = EXCLUDED.value, stored_at = EXCLUDED.stored_at
+		WHERE spacex_cache.stored_at < EXCLUDED.stored_at`
+	_, err := o.db.Exec(ctx, q, key, value, storedAt.UTC())
+	return err
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+// ReplaceSpaceXSchedule replaces the synced launchpads and launches at once,
+// so that the bookings never see half of a sync.
+func (o *Store) ReplaceSpaceXSchedule(ctx context.Context, launchpads []entity.SpaceXLaunchpad,
+	launches []entity.SpaceXLaunch, syncedAt time.Time) error {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM spacex_launches`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM spacex_launchpads`); err != nil {
+		return err
+	}
+	for _, l := range launchpads {
+		if _, err := tx.Exec(ctx, `INSERT INTO spacex_launchpads(id, status) VALUES($1, $2)`, l.ID, l.Status); err != nil {
+			return err
+		}
+	}
+	q := `INSERT INTO spacex_launches(id, launchpad_id, launch_date, date_precision) VALUES($1, $2, $3, $4)`
+	for _, l := range launches {
+		if _, err := tx.Exec(ctx, q, l.ID, l.LaunchpadID, l.Date.UTC(), l.DatePrecision); err != nil {
+			return err
+		}
+	}
+	q = `INSERT INTO spacex_syncs(synced_at) VALUES($1)
+		ON CONFLICT(id) DO UPDATE SET synced_at = EXCLUDED.synced_at`
+	if _, err := tx.Exec(ctx, q, syncedAt.UTC()); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetSpaceXSyncedAt returns the time of the last sync, entity.ErrNotFound when there was none.
+func (o *Store) GetSpaceXSyncedAt(ctx context.Context) (time.Time, error) {
+	var syncedAt time.Time
+	err := o.db.QueryRow(ctx, `SELECT synced_at FROM spacex_syncs`).Scan(&syncedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return syncedAt, entity.ErrNotFound
+	}
+	return syncedAt, err
+}
+
+func (o *Store) GetSpaceXLaunchpad(ctx context.Context, id string) (entity.SpaceXLaunchpad, error) {
+	ans := entity.SpaceXLaunchpad{ID: id}
+	err := o.db.QueryRow(ctx, `SELECT status FROM spacex_launchpads WHERE id = $1`, id).Scan(&ans.Status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ans, entity.ErrNotFound
+	}
+	return ans, err
+}
+
+// GetSpaceXLaunches returns the synced launches of the launchpad by date.
+func (o *Store) GetSpaceXLaunches(ctx context.Context, launchpadID string) ([]entity.SpaceXLaunch, error) {
+	q := `SELECT id, launchpad_id, launch_date, date_precision FROM spacex_launches
+		WHERE launchpad_id = $1 ORDER BY launch_date, id`
+	rows, err := o.db.Query(ctx, q, launchpadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []entity.SpaceXLaunch
+	for rows.Next() {
+		var l entity.SpaceXLaunch
+		if err := rows.Scan(&l.ID, &l.LaunchpadID, &l.Date, &l.DatePrecision); err != nil {
+			return nil, err
+		}
+		items = append(items, l)
+	}
+	return items, rows.Err()
+}
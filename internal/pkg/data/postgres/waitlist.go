@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+const selectWaitlistQ = `SELECT
+			W.id, W.first_name, W.last_name, W.gender, W.birthday,
+			W.launchpad_id, W.launch_date, W.status, W.booking_id, W.created_at, W.promoted_at,
+			D.id, D.name
+		FROM waitlist W
+		JOIN destinations D ON D.id = W.destination_id
+		`
+
+func scanWaitlistEntry(row pgx.Row) (entity.WaitlistEntry, error) {
+	var item entity.WaitlistEntry
+	err := row.Scan(
+		&item.ID, &item.FirstName, &item.LastName, &item.Gender, &item.Birthday,
+		&item.LaunchpadID, &item.Date, &item.Status, &item.BookingID, &item.CreatedAt, &item.PromotedAt,
+		&item.Destination.ID, &item.Destination.Name,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return item, entity.ErrNotFound
+	}
+	return item, err
+}
+
+func (o *Store) CreateWaitlistEntry(ctx context.Context, e entity.WaitlistEntry) (entity.WaitlistEntry, error) {
+	q := `INSERT INTO waitlist(id, first_name, last_name, gender, birthday,
+			launchpad_id, destination_id, launch_date, status, created_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	e.ID = uuid.New()
+	e.Status = entity.WaitlistStatusWaiting
+	e.CreatedAt = time.Now().UTC()
+	_, err := o.db.Exec(ctx, q, e.ID, e.FirstName, e.LastName, e.Gender, e.Birthday,
+		e.LaunchpadID, e.Destination.ID, e.Date, e.Status, e.CreatedAt)
+	if isUniqueViolation(err) {
+		return e, entity.ErrAlreadyExists
+	}
+	return e, err
+}
+
+func (o *Store) GetWaitlistEntryById(ctx context.Context, id string) (entity.WaitlistEntry, error) {
+	return scanWaitlistEntry(o.db.QueryRow(ctx, selectWaitlistQ+" WHERE W.id = $1", id))
+}
+
+// WaitingEntries returns the entries still waiting for a launchpad and date, first come first.
+func (o *Store) WaitingEntries(ctx context.Context, launchpadId string, date time.Time) ([]entity.WaitlistEntry, error) {
+	q := selectWaitlistQ + ` WHERE W.launchpad_id = $1 AND W.launch_date = $2 AND W.status = $3
+		ORDER BY W.created_at, W.id`
+	rows, err := o.db.Query(ctx, q, launchpadId, date, entity.WaitlistStatusWaiting)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []entity.WaitlistEntry
+	for rows.Next() {
+		item, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// PromoteWaitlistEntry books the passenger of a waiting entry on the flight
+// and marks the entry as promoted, all or nothing.
+func (o *Store) PromoteWaitlistEntry(ctx context.Context, id string, u entity.User, f entity.Flight, price int64,
+	payBy *time.Time) (entity.Booking, error) {
+	uq := `UPDATE waitlist SET status = $2, booking_id = $3, promoted_at = $4 WHERE id = $1`
+
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return entity.Booking{}, err
+	}
+	defer tx.Rollback(ctx)
+	e, err := scanWaitlistEntry(tx.QueryRow(ctx, selectWaitlistQ+" WHERE W.id = $1 FOR UPDATE OF W", id))
+	if err != nil {
+		return entity.Booking{}, err
+	}
+	if e.Status != entity.WaitlistStatusWaiting {
+		return entity.Booking{}, entity.ErrInvalidStatusTransition
+	}
+	nb, err := o.createBookingTx(ctx, tx, newBooking(u, f, price, payBy))
+	if err != nil {
+		return nb, err
+	}
+	if _, err := tx.Exec(ctx, uq, e.ID, entity.WaitlistStatusPromoted, nb.ID, time.Now().UTC()); err != nil {
+		return nb, err
+	}
+	return nb, tx.Commit(ctx)
+}
+
+func (o *Store) MarkWaitlistEntryStale(ctx context.Context, id string) error {
+	q := `UPDATE waitlist SET status = $2 WHERE id = $1 AND status = $3`
+	_, err := o.db.Exec(ctx, q, id, entity.WaitlistStatusStale, entity.WaitlistStatusWaiting)
+	return err
+}
+
+func (o *Store) IsBookedOn(ctx context.Context, u entity.User, launchpadId string, date time.Time) (bool, error) {
+	userQ := `SELECT id FROM users WHERE ` + userIdentityCond
+	args := []interface{}{u.FirstName, u.LastName, u.Gender, u.Birthday}
+	if u.ID != uuid.Nil {
+		userQ, args = `SELECT $1::uuid`, []interface{}{u.ID}
+	}
+	q := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM bookings B JOIN flights F ON F.id = B.flight_id
+		WHERE B.user_id IN (%s) AND F.launchpad_id = $%d AND F.launch_date = $%d AND B.status = ANY($%d))`,
+		userQ, len(args)+1, len(args)+2, len(args)+3)
+	var ans bool
+	err := o.db.QueryRow(ctx, q, append(args, launchpadId, date, entity.OccupyingBookingStatuses)...).Scan(&ans)
+	return ans, err
+}
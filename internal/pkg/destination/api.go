@@ -0,0 +1,124 @@
+package destination
+
+import (
+	"net/http"
+	"strings"
+
+	"spacetrouble/pkg/apiutils"
+)
+
+func DestinationHandler(srv DestinationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			create(srv, w, r)
+		} else if r.Method == http.MethodGet {
+			all(srv, w, r)
+		}
+	}
+}
+
+// DestinationItemHandler serves the routes under /destinations/{id}.
+// It expects the /destinations/ prefix to be already stripped from the path.
+func DestinationItemHandler(srv DestinationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(r.URL.Path, "/")
+		switch r.Method {
+		case http.MethodGet:
+			get(srv, id, w, r)
+		case http.MethodPatch:
+			rename(srv, id, w, r)
+		case http.MethodDelete:
+			remove(srv, id, w, r)
+		}
+	}
+}
+
+func all(srv DestinationService, w http.ResponseWriter, r *http.Request) {
+	ans, err := srv.AllDestinations(r.Context())
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusOK, ans)
+}
+
+func create(srv DestinationService, w http.ResponseWriter, r *http.Request) {
+	var dstReq DestinationRequest
+	if err := apiutils.JsonDecodeBody(r, &dstReq); err != nil {
+		ae := apiutils.NewBadRequest("error json decoding body")
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	if err := dstReq.Validate(); err != nil {
+		ae := apiutils.NewBadRequest(err.Error())
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	ans, err := srv.CreateDestination(r.Context(), dstReq)
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusCreated, ans)
+}
+
+func get(srv DestinationService, id string, w http.ResponseWriter, r *http.Request) {
+	ans, err := srv.GetDestination(r.Context(), id)
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusOK, ans)
+}
+
+func rename(srv DestinationService, id string, w http.ResponseWriter, r *http.Request) {
+	var dstReq DestinationRequest
+	if err := apiutils.JsonDecodeBody(r, &dstReq); err != nil {
+		ae := apiutils.NewBadRequest("error json decoding body")
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	if err := dstReq.Validate(); err != nil {
+		ae := apiutils.NewBadRequest(err.Error())
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	ans, err := srv.RenameDestination(r.Context(), id, dstReq)
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusOK, ans)
+}
+
+func remove(srv DestinationService, id string, w http.ResponseWriter, r *http.Request) {
+	if err := srv.DeleteDestination(r.Context(), id); err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusNoContent, nil)
+}
+
+func getApiError(err error) apiutils.ApiError {
+	ae := apiutils.ApiError{Msg: err.Error()}
+	switch err {
+	case ErrInvalidUUID:
+		ae.StatusCode = http.StatusBadRequest
+	case ErrDestinationNotFound:
+		ae.StatusCode = http.StatusNotFound
+	case ErrDestinationExists, ErrDestinationInUse:
+		ae.StatusCode = http.StatusConflict
+	default:
+		ae.StatusCode = http.StatusInternalServerError
+	}
+	return ae
+}
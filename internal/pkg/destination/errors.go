@@ -0,0 +1,12 @@
+package destination
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidUUID         = errors.New("invalid uuid")
+	ErrDestinationNotFound = errors.New("destination does not exist")
+	ErrDestinationExists   = errors.New("destination with the same name already exists")
+	ErrDestinationInUse    = errors.New("destination has flights or waiting passengers")
+)
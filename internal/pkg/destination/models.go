@@ -0,0 +1,26 @@
+package destination
+
+import (
+	"errors"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+type DestinationRequest struct {
+	Name string
+}
+
+func (o *DestinationRequest) Validate() error {
+	if len(o.Name) == 0 || len(o.Name) > 100 {
+		return errors.New("Name must be more than 0 and less than 100 chars")
+	}
+	return nil
+}
+
+type DestinationResponse struct {
+	entity.Destination
+}
+
+type AllDestinationsResponse struct {
+	Destinations []DestinationResponse `json:"destinations"`
+}
@@ -0,0 +1,101 @@
+package destination
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+type DestinationService interface {
+	AllDestinations(ctx context.Context) (AllDestinationsResponse, error)
+	GetDestination(ctx context.Context, id string) (DestinationResponse, error)
+	CreateDestination(ctx context.Context, req DestinationRequest) (DestinationResponse, error)
+	RenameDestination(ctx context.Context, id string, req DestinationRequest) (DestinationResponse, error)
+	DeleteDestination(ctx context.Context, id string) error
+}
+
+type destinationSrv struct {
+	store entity.Store
+}
+
+func NewDestinationService(store entity.Store) *destinationSrv {
+	ans := destinationSrv{
+		store: store,
+	}
+	return &ans
+}
+
+func (o *destinationSrv) AllDestinations(ctx context.Context) (AllDestinationsResponse, error) {
+	ans := AllDestinationsResponse{
+		Destinations: make([]DestinationResponse, 0),
+	}
+	destinations, err := o.store.GetAllDestinations(ctx)
+	if err != nil {
+		return ans, err
+	}
+	for i := range destinations {
+		ans.Destinations = append(ans.Destinations, DestinationResponse{Destination: destinations[i]})
+	}
+	return ans, nil
+}
+
+func (o *destinationSrv) GetDestination(ctx context.Context, id string) (DestinationResponse, error) {
+	var ans DestinationResponse
+	if _, err := uuid.Parse(id); err != nil {
+		return ans, ErrInvalidUUID
+	}
+	dst, err := o.store.GetDestinationById(ctx, id)
+	if err != nil {
+		return ans, mapStoreError(err)
+	}
+	ans.Destination = dst
+	return ans, nil
+}
+
+func (o *destinationSrv) CreateDestination(ctx context.Context, req DestinationRequest) (DestinationResponse, error) {
+	var ans DestinationResponse
+	dst, err := o.store.CreateDestination(ctx, req.Name)
+	if err != nil {
+		return ans, mapStoreError(err)
+	}
+	ans.Destination = dst
+	return ans, nil
+}
+
+func (o *destinationSrv) RenameDestination(ctx context.Context, id string, req DestinationRequest) (DestinationResponse, error) {
+	var ans DestinationResponse
+	if _, err := uuid.Parse(id); err != nil {
+		return ans, ErrInvalidUUID
+	}
+	dst, err := o.store.RenameDestination(ctx, id, req.Name)
+	if err != nil {
+		return ans, mapStoreError(err)
+	}
+	ans.Destination = dst
+	return ans, nil
+}
+
+// DeleteDestination refuses to delete destinations that have flights or waiting passengers,
+// since that would delete their bookings or waitlist entries too.
+func (o *destinationSrv) DeleteDestination(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidUUID
+	}
+	return mapStoreError(o.store.DeleteDestination(ctx, id))
+}
+
+func mapStoreError(err error) error {
+	switch {
+	case errors.Is(err, entity.ErrNotFound):
+		return ErrDestinationNotFound
+	case errors.Is(err, entity.ErrAlreadyExists):
+		return ErrDestinationExists
+	case errors.Is(err, entity.ErrInUse):
+		return ErrDestinationInUse
+	default:
+		return err
+	}
+}
@@ -0,0 +1,166 @@
+package destination
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"spacetrouble/internal/pkg/data/postgres"
+	"spacetrouble/internal/pkg/entity"
+
+	"spacetrouble/pkg/testutils"
+)
+
+func TestMain(m *testing.M) {
+	workingDir, _ := os.Getwd()
+	rootDir := strings.Replace(workingDir, "internal/pkg/destination", "", 1)
+
+	ctx := context.Background()
+	postgresContainer := testutils.SpinPostgresContainer(ctx, rootDir)
+
+	defer postgresContainer.Terminate(ctx)
+
+	exitCode := m.Run()
+
+	os.Exit(exitCode)
+}
+
+func getStoreAndDb() (entity.Store, *pgxpool.Pool, error) {
+	db, err := testutils.GetTestDb()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := db.Ping(context.Background()); err != nil {
+		return nil, nil, err
+	}
+	store := postgres.NewStore(db)
+	return store, db, nil
+}
+
+// cleanDatabase drops the database of the test.
+func cleanDatabase(db *pgxpool.Pool) {
+	if err := testutils.DropTestDb(db); err != nil {
+		panic(err)
+	}
+}
+
+func TestCreateAndRenameDestination(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+
+	srv := NewDestinationService(store)
+
+	created, err := srv.CreateDestination(context.Background(), DestinationRequest{Name: "test-Venus"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = srv.CreateDestination(context.Background(), DestinationRequest{Name: "test-Venus"})
+	if err != ErrDestinationExists {
+		t.Errorf("expected %v but got %v", ErrDestinationExists, err)
+		return
+	}
+
+	renamed, err := srv.RenameDestination(context.Background(), created.ID.String(), DestinationRequest{Name: "test-Mercury"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if renamed.ID != created.ID || renamed.Name != "test-Mercury" {
+		t.Errorf("expected destination to be renamed but got %+v", renamed.Destination)
+		return
+	}
+
+	_, err = srv.RenameDestination(context.Background(), uuid.New().String(), DestinationRequest{Name: "test-Vulcan"})
+	if err != ErrDestinationNotFound {
+		t.Errorf("expected %v but got %v", ErrDestinationNotFound, err)
+		return
+	}
+}
+
+func TestDeleteDestination(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+
+	srv := NewDestinationService(store)
+
+	unused, err := srv.CreateDestination(context.Background(), DestinationRequest{Name: "test-Venus"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := srv.DeleteDestination(context.Background(), unused.ID.String()); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := srv.GetDestination(context.Background(), unused.ID.String()); err != ErrDestinationNotFound {
+		t.Errorf("expected %v but got %v", ErrDestinationNotFound, err)
+		return
+	}
+
+	used, err := srv.CreateDestination(context.Background(), DestinationRequest{Name: "test-Mercury"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	user := entity.User{
+		FirstName: "Giorgos",
+		LastName:  "Papadopoulos",
+		Gender:    "m",
+		Birthday:  time.Date(1923, 11, 13, 0, 0, 0, 0, time.UTC),
+	}
+	flight := entity.Flight{
+		LaunchpadID: strings.Replace(uuid.New().String(), "-", "", -1)[:24],
+		Destination: used.Destination,
+		Date:        time.Date(2049, 4, 6, 0, 0, 0, 0, time.UTC),
+		Capacity:    10,
+	}
+	if _, err := store.CreateBooking(context.Background(), user, flight, 0, nil); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := srv.DeleteDestination(context.Background(), used.ID.String()); err != ErrDestinationInUse {
+		t.Errorf("expected %v but got %v", ErrDestinationInUse, err)
+		return
+	}
+
+	waited, err := srv.CreateDestination(context.Background(), DestinationRequest{Name: "test-Saturn"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = store.CreateWaitlistEntry(context.Background(), entity.WaitlistEntry{
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Gender:      user.Gender,
+		Birthday:    user.Birthday,
+		LaunchpadID: flight.LaunchpadID,
+		Destination: waited.Destination,
+		Date:        flight.Date,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := srv.DeleteDestination(context.Background(), waited.ID.String()); err != ErrDestinationInUse {
+		t.Errorf("expected %v but got %v", ErrDestinationInUse, err)
+		return
+	}
+}
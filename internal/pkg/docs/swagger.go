@@ -16,6 +16,8 @@ package docs
 
 import (
 	"spacetrouble/internal/pkg/booking"
+	"spacetrouble/internal/pkg/destination"
+	"spacetrouble/internal/pkg/flight"
 	//"spacetrouble/pkg/apiutils"
 )
 
@@ -30,8 +32,9 @@ import (
 //   200:
 
 // swagger:route GET /v1/bookings Bookings All
-// Fetches all bookings.
-// Supports pagination via the cursor query parameter
+// Fetches all bookings, optionally filtered and sorted.
+// Supports pagination via the cursor query parameter, with the cursor or prev_cursor of a page.
+// A cursor is signed and only valid for the filters and the sort it was issued with
 // ---
 // produces:
 //  - application/json
@@ -46,6 +49,34 @@ type BookingAllParamsWrapper struct {
 	Limit int `json:"limit"`
 	// in:query
 	Cursor string `json:"cursor"`
+	// active, cancelled, held, expired or pending_payment
+	// in:query
+	Status string `json:"status"`
+	// in:query
+	Destination string `json:"destination"`
+	// in:query
+	Launchpad string `json:"launchpad"`
+	// Launch date from, inclusive, formatted as 2006-01-02
+	// in:query
+	From string `json:"from"`
+	// Launch date to, inclusive, formatted as 2006-01-02
+	// in:query
+	To string `json:"to"`
+	// Part of the passenger name, case insensitive
+	// in:query
+	Passenger string `json:"passenger"`
+	// Creation date from, inclusive, formatted as 2006-01-02
+	// in:query
+	CreatedFrom string `json:"created_from"`
+	// Creation date to, inclusive, formatted as 2006-01-02
+	// in:query
+	CreatedTo string `json:"created_to"`
+	// created_at, the default, or launch_date, prefixed by - for the descending order
+	// in:query
+	Sort string `json:"sort"`
+	// exact or estimate to count the bookings matching the filters
+	// in:query
+	Total string `json:"total"`
 }
 
 // OK
@@ -54,9 +85,14 @@ type BookingAllParamsWrapper struct {
 // An AllBookingsResponse obj
 // swagger:response AllBookingsPaginated
 type AllBookingsPaginated struct {
-	Limit    int                      `json:"limit"`
-	Cursor   string                   `json:"cursor"`
-	Bookings []BookingSuccessResponse `json:"bookings"`
+	Limit      int    `json:"limit"`
+	Cursor     string `json:"cursor"`
+	PrevCursor string `json:"prev_cursor"`
+	HasMore    bool   `json:"has_more"`
+	// only set when asked for with the total query parameter
+	Total          int64                    `json:"total"`
+	TotalEstimated bool                     `json:"total_estimated"`
+	Bookings       []BookingSuccessResponse `json:"bookings"`
 }
 
 type AllBookingsPaginatedResp struct {
@@ -64,14 +100,19 @@ type AllBookingsPaginatedResp struct {
 
 // swagger:route POST /v1/bookings Bookings Booking
 // Attempts to make a new booking to a space destination.
+// When the flight is full or the launchpad unavailable and Waitlist is set
+// the passenger is queued in the waitlist instead and 202 is returned.
 // ---
 // produces:
 // - application/json
 // responses:
 // 201: BookingSuccessResponse
+// 202: WaitlistResponse
 // 400:
 // 404:
 // 409:
+// 422:
+// 503:
 // 500:
 
 // Created
@@ -90,4 +131,511 @@ type BookingRequest struct {
 	// in:body
 	// required:true
 	Body booking.BookingRequest
+	// Repeating the request with the same key replays the original response.
+	// Reusing the key with a different body returns 422.
+	// in:header
+	IdempotencyKey string `json:"Idempotency-Key"`
+}
+
+// swagger:route GET /v1/bookings/{id} Bookings GetBooking
+// Fetches a single booking.
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: BookingSuccessResponse
+// 400:
+// 404:
+// 500:
+
+// swagger:parameters GetBooking
+type GetBookingParams struct {
+	// in:path
+	// required:true
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /v1/bookings/{id} Bookings CancelBooking
+// Cancels an active booking.
+// When it was the last active booking of its flight the launchpad is freed for that date.
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: BookingSuccessResponse
+// 400:
+// 404:
+// 409:
+// 500:
+
+// swagger:route POST /v1/bookings/{id}/cancel Bookings CancelBookingPost
+// Cancels an active booking. Same as DELETE /v1/bookings/{id}.
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: BookingSuccessResponse
+// 400:
+// 404:
+// 409:
+// 500:
+
+// swagger:parameters CancelBooking CancelBookingPost
+type CancelBookingParams struct {
+	// in:path
+	// required:true
+	ID string `json:"id"`
+	// The cancellation reason, optional
+	// in:body
+	Body booking.CancelBookingRequest
+}
+
+// swagger:route GET /v1/destinations Destinations AllDestinations
+// Fetches all destinations.
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: AllDestinationsResponse
+// 500:
+
+// OK
+// The operation was processed successfully
+//
+// swagger:response AllDestinationsResponse
+type AllDestinationsResponse struct {
+	// in:body
+	Body destination.AllDestinationsResponse
+}
+
+// swagger:route POST /v1/destinations Destinations CreateDestination
+// Creates a new destination.
+// ---
+// produces:
+// - application/json
+// responses:
+// 201: DestinationResponse
+// 400:
+// 409:
+// 500:
+
+// swagger:parameters CreateDestination
+type CreateDestinationParams struct {
+	// in:body
+	// required:true
+	Body destination.DestinationRequest
+}
+
+// swagger:route GET /v1/destinations/{id} Destinations GetDestination
+// Fetches a single destination.
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: DestinationResponse
+// 400:
+// 404:
+// 500:
+
+// swagger:route PATCH /v1/destinations/{id} Destinations RenameDestination
+// Renames a destination.
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: DestinationResponse
+// 400:
+// 404:
+// 409:
+// 500:
+
+// swagger:route DELETE /v1/destinations/{id} Destinations DeleteDestination
+// Deletes a destination.
+// Destinations with flights or waiting passengers cannot be deleted, 409 is returned instead.
+// ---
+// produces:
+// - application/json
+// responses:
+// 204:
+// 400:
+// 404:
+// 409:
+// 500:
+
+// swagger:parameters GetDestination DeleteDestination
+type DestinationParams struct {
+	// in:path
+	// required:true
+	ID string `json:"id"`
+}
+
+// swagger:parameters RenameDestination
+type RenameDestinationParams struct {
+	// in:path
+	// required:true
+	ID string `json:"id"`
+	// in:body
+	// required:true
+	Body destination.DestinationRequest
+}
+
+// OK
+// The operation was processed successfully
+//
+// swagger:response DestinationResponse
+type DestinationResponse struct {
+	// in:body
+	Body destination.DestinationResponse
+}
+
+// swagger:route GET /v1/flights Flights AllFlights
+// Fetches flights with their number of passengers.
+// Supports pagination via the cursor query parameter
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: AllFlightsResponse
+// 400:
+// 500:
+
+// swagger:parameters AllFlights
+type FlightAllParamsWrapper struct {
+	// in:query
+	Launchpad string `json:"launchpad"`
+	// in:query
+	Destination string `json:"destination"`
+	// scheduled or cancelled
+	// in:query
+	Status string `json:"status"`
+	// Launch date from, inclusive, formatted as 2006-01-02
+	// in:query
+	From string `json:"from"`
+	// Launch date to, inclusive, formatted as 2006-01-02
+	// in:query
+	To string `json:"to"`
+	// Only flights with (true) or without (false) active bookings,
+	// held and pending payment bookings are not counted
+	// in:query
+	HasActiveBookings bool `json:"has_active_bookings"`
+	// in:query
+	Limit int `json:"limit"`
+	// in:query
+	Cursor string `json:"cursor"`
+}
+
+// OK
+// The operation was processed successfully
+//
+// swagger:response AllFlightsResponse
+type AllFlightsResponse struct {
+	// in:body
+	Body flight.AllFlightsResponse
+}
+
+// swagger:route GET /v1/waitlist/{id} Waitlist GetWaitlistEntry
+// Fetches a waitlist entry.
+// Once the passenger is booked the status is promoted and BookingID is the new booking.
+// The status is stale when the passenger got booked on the flight otherwise.
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: WaitlistResponse
+// 400:
+// 404:
+// 500:
+
+// swagger:parameters GetWaitlistEntry
+type GetWaitlistEntryParams struct {
+	// in:path
+	// required:true
+	ID string `json:"id"`
+}
+
+// A WaitlistResponse Object
+// swagger:response WaitlistResponse
+type WaitlistResponse struct {
+	// in:body
+	Body booking.WaitlistResponse
+}
+
+// swagger:route GET /v1/users/{id} Users GetPassenger
+// Fetches a passenger.
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: PassengerResponse
+// 400:
+// 404:
+// 500:
+
+// swagger:parameters GetPassenger
+type GetPassengerParams struct {
+	// in:path
+	// required:true
+	ID string `json:"id"`
+}
+
+// A PassengerResponse Object
+// swagger:response PassengerResponse
+type PassengerResponse struct {
+	// in:body
+	Body booking.PassengerResponse
+}
+
+// swagger:route GET /v1/users/{id}/bookings Users PassengerBookings
+// Fetches every booking of a passenger, cancelled ones included.
+// Supports pagination via the cursor query parameter
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: AllBookingsPaginated
+// 400:
+// 404:
+// 500:
+
+// swagger:parameters PassengerBookings
+type PassengerBookingsParams struct {
+	// in:path
+	// required:true
+	ID string `json:"id"`
+	// in:query
+	Limit int `json:"limit"`
+	// in:query
+	Cursor string `json:"cursor"`
+}
+
+// swagger:route POST /v1/holds Bookings HoldBooking
+// Holds a seat for some minutes.
+// The booking is held until it is confirmed or it expires.
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// responses:
+// 201: BookingSuccessResponse
+// 400:
+// 404:
+// 409:
+// 503:
+// 500:
+
+// swagger:parameters HoldBooking
+type HoldBookingParams struct {
+	// in:body
+	Body booking.HoldRequest
+}
+
+// swagger:route POST /v1/bookings/{id}/confirm Bookings ConfirmBooking
+// Pays a held or pending payment booking.
+// The booking becomes active once its payment is captured.
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: BookingSuccessResponse
+// 400:
+// 402:
+// 404:
+// 409:
+// 500:
+
+// swagger:parameters ConfirmBooking
+type ConfirmBookingParams struct {
+	// in:path
+	// required:true
+	ID string `json:"id"`
+}
+
+// swagger:route POST /v1/bookings/group Bookings MakeGroupBooking
+// Books several passengers on the same flight.
+// Either all the passengers are booked or none of them.
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// responses:
+// 201: GroupBookingResponse
+// 400:
+// 404:
+// 409:
+// 503:
+// 500:
+
+// swagger:parameters MakeGroupBooking
+type GroupBookingParams struct {
+	// in:body
+	Body booking.GroupBookingRequest
+}
+
+// A GroupBookingResponse Object
+// swagger:response GroupBookingResponse
+type GroupBookingResponse struct {
+	// in:body
+	Body booking.GroupBookingResponse
+}
+
+// swagger:route GET /v1/quotes Bookings Quote
+// Prices a booking without booking.
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: QuoteResponse
+// 400:
+// 404:
+// 409:
+// 503:
+// 500:
+
+// swagger:parameters Quote
+type QuoteParams struct {
+	// in:query
+	// required:true
+	Launchpad string `json:"launchpad"`
+	// in:query
+	// required:true
+	Destination string `json:"destination"`
+	// in:query
+	// required:true
+	Date string `json:"date"`
+	// in:query
+	Birthday string `json:"birthday"`
+	// in:query
+	Passenger string `json:"passenger"`
+}
+
+// A QuoteResponse Object
+// swagger:response QuoteResponse
+type QuoteResponse struct {
+	// in:body
+	Body booking.QuoteResponse
+}
+
+// swagger:route PATCH /v1/bookings/{id} Bookings RebookBooking
+// Moves a booking to another date, launchpad or destination.
+// The booking keeps its ID, status and price, the new flight is not quoted again.
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// responses:
+// 200: BookingSuccessResponse
+// 400:
+// 404:
+// 409:
+// 503:
+// 500:
+
+// swagger:parameters RebookBooking
+type RebookBookingParams struct {
+	// in:path
+	// required:true
+	ID string `json:"id"`
+	// in:body
+	Body booking.RebookRequest
+}
+
+// swagger:route GET /v1/bookings/{id}/history Bookings BookingHistory
+// Fetches every change of a booking, oldest first.
+// ---
+// produces:
+// - application/json
+// responses:
+// 200: BookingHistoryResponse
+// 400:
+// 404:
+// 500:
+
+// swagger:parameters BookingHistory
+type BookingHistoryParams struct {
+	// in:path
+	// required:true
+	ID string `json:"id"`
+}
+
+// A BookingHistoryResponse Object
+// swagger:response BookingHistoryResponse
+type BookingHistoryResponse struct {
+	// in:body
+	Body booking.BookingHistoryResponse
+}
+
+// swagger:route GET /v1/bookings/export Bookings ExportBookings
+// Streams every booking matching the filters as CSV, the default, or NDJSON, one booking per line.
+// An NDJSON export failing after it started ends with an {"error":"export interrupted"} line.
+// ---
+// produces:
+// - text/csv
+// - application/x-ndjson
+// responses:
+// 200:
+// 400:
+// 500:
+
+// swagger:parameters ExportBookings
+type ExportBookingsParams struct {
+	// csv or ndjson
+	// in:query
+	Format string `json:"format"`
+	// active, cancelled, held, expired or pending_payment
+	// in:query
+	Status string `json:"status"`
+	// in:query
+	Destination string `json:"destination"`
+	// in:query
+	Launchpad string `json:"launchpad"`
+	// Launch date from, inclusive, formatted as 2006-01-02
+	// in:query
+	From string `json:"from"`
+	// Launch date to, inclusive, formatted as 2006-01-02
+	// in:query
+	To string `json:"to"`
+	// Part of the passenger name, case insensitive
+	// in:query
+	Passenger string `json:"passenger"`
+	// Creation date from, inclusive, formatted as 2006-01-02
+	// in:query
+	CreatedFrom string `json:"created_from"`
+	// Creation date to, inclusive, formatted as 2006-01-02
+	// in:query
+	CreatedTo string `json:"created_to"`
+	// created_at, the default, or launch_date, prefixed by - for the descending order
+	// in:query
+	Sort string `json:"sort"`
+}
+
+// swagger:route POST /v1/bookings/import Bookings ImportBookings
+// Books every row of a partner manifest, a CSV file with the text/csv content type or a JSON array
+// of booking requests, and reports the outcome of every row.
+// A row that does not validate or cannot be booked is rejected with the reason, the other rows are still booked.
+// Bookings are active, or pending payment until the PayBy of their row.
+// ---
+// consumes:
+// - application/json
+// - text/csv
+// produces:
+// - application/json
+// responses:
+// 200: ImportReport
+// 400:
+// 500:
+
+// swagger:parameters ImportBookings
+type ImportBookingsParams struct {
+	// in:body
+	Body []booking.ImportRequest
+}
+
+// An ImportReport Object
+// swagger:response ImportReport
+type ImportReport struct {
+	// in:body
+	Body booking.ImportReport
 }
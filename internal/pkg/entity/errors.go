@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"errors"
+)
+
+var (
+	ErrNotFound                = errors.New("not found")
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+	ErrFlightNotScheduled      = errors.New("flight is not scheduled")
+	ErrAlreadyExists           = errors.New("already exists")
+	ErrInUse                   = errors.New("in use")
+	ErrFlightFull              = errors.New("flight is full")
+	ErrAlreadyBooked           = errors.New("passenger already booked on the flight")
+	ErrHoldExpired             = errors.New("hold has expired")
+)
@@ -9,17 +9,132 @@ import (
 )
 
 const (
-	BookingStatusActive = "active"
+	BookingStatusActive    = "active"
+	BookingStatusCancelled = "cancelled"
+	BookingStatusHeld      = "held"
+	BookingStatusExpired   = "expired"
+	// BookingStatusPendingPayment is a booking waiting to be paid to become active
+	BookingStatusPendingPayment = "pending_payment"
+
+	FlightStatusScheduled = "scheduled"
+	FlightStatusCancelled = "cancelled"
+
+	WaitlistStatusWaiting  = "waiting"
+	WaitlistStatusPromoted = "promoted"
+	// WaitlistStatusStale is an entry whose passenger got a seat on the flight otherwise
+	WaitlistStatusStale = "stale"
+
+	PaymentKindCapture = "capture"
+	PaymentKindRefund  = "refund"
+	// PaymentKindRefundPending is a refund the payment provider failed, retried until it is a refund
+	PaymentKindRefundPending = "refund_pending"
+
+	BookingEventCreated   = "created"
+	BookingEventConfirmed = "confirmed"
+	BookingEventCancelled = "cancelled"
+	BookingEventExpired   = "expired"
+	BookingEventRebooked  = "rebooked"
+
+	// DefaultActor is recorded in the booking history when the context has no actor.
+	DefaultActor = "system"
 )
 
+// bookingTransitions lists for every booking status the statuses it can move to.
+var bookingTransitions = map[string][]string{
+	BookingStatusPendingPayment: {BookingStatusActive, BookingStatusCancelled, BookingStatusExpired},
+	BookingStatusActive:         {BookingStatusCancelled},
+	BookingStatusHeld:           {BookingStatusActive, BookingStatusCancelled, BookingStatusExpired},
+}
+
+// OccupyingBookingStatuses are the booking statuses that take a seat on the flight.
+var OccupyingBookingStatuses = []string{BookingStatusActive, BookingStatusHeld, BookingStatusPendingPayment}
+
+func IsOccupyingBookingStatus(status string) bool {
+	for _, s := range OccupyingBookingStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func CanTransitionBooking(from, to string) bool {
+	for _, s := range bookingTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+type actorKey struct{}
+
+// WithActor returns a context recording who makes the changes in the booking history.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return DefaultActor
+}
+
 type Store interface {
 	CreateDestination(ctx context.Context, name string) (Destination, error)
 	GetAllDestinations(ctx context.Context) ([]Destination, error)
 	GetDestinationById(ctx context.Context, id string) (Destination, error)
-	CreateBooking(ctx context.Context, u User, f Flight) (Booking, error)
+	RenameDestination(ctx context.Context, id string, name string) (Destination, error)
+	DeleteDestination(ctx context.Context, id string) error
+	GetUserById(ctx context.Context, id string) (User, error)
+	// CreateBooking books the passenger on the flight, pending payment until payBy or active right away
+	// without payBy, e.g. when paid to a partner. When the user has no ID the passenger is matched
+	// on name, gender and birthday or registered.
+	CreateBooking(ctx context.Context, u User, f Flight, price int64, payBy *time.Time) (Booking, error)
+	// CreateGroupBooking books all the passengers on the flight or none of them.
+	// prices[i] is the price of users[i].
+	CreateGroupBooking(ctx context.Context, users []User, prices []int64, f Flight, payBy time.Time) ([]Booking, error)
+	// CreateHold reserves a seat on the flight until expiresAt, like CreateBooking.
+	CreateHold(ctx context.Context, u User, f Flight, price int64, expiresAt time.Time) (Booking, error)
+	// ConfirmBooking activates a held or pending payment booking recording its payment capture.
+	ConfirmBooking(ctx context.Context, id string, capture Payment) (Booking, error)
+	// CapturedPayment returns the payment capture of the booking, ErrNotFound when it was not paid.
+	CapturedPayment(ctx context.Context, bookingId string) (Payment, error)
+	CreatePayment(ctx context.Context, p Payment) (Payment, error)
+	// PendingRefunds returns the refunds the payment provider failed, oldest first.
+	PendingRefunds(ctx context.Context) ([]Payment, error)
+	// ResolvePendingRefund replaces a pending refund by the refund made with the provider reference.
+	ResolvePendingRefund(ctx context.Context, id uuid.UUID, providerRef string) (Payment, error)
+	// ExpireHolds expires the holds and the pending payment bookings that ran out before now and returns them.
+	ExpireHolds(ctx context.Context, now time.Time) ([]Booking, error)
+	CancelBooking(ctx context.Context, id string, reason string) (Booking, error)
+	// RebookBooking moves a booking taking a seat to another flight, keeping its ID.
+	RebookBooking(ctx context.Context, id string, f Flight) (Booking, error)
+	GetBookingById(ctx context.Context, id string) (Booking, error)
+	// BookingEvents returns the history of the booking, oldest first.
+	BookingEvents(ctx context.Context, bookingId string) ([]BookingEvent, error)
+	// CountSeatsTaken counts the bookings holding a seat on the flight.
+	CountSeatsTaken(ctx context.Context, flightId string) (int, error)
 	SelectFlights(ctx context.Context, filters map[string]interface{}) ([]Flight, error)
-	GetLaunchPadWeekAvailability(ctx context.Context, launchpadId, destinationId string, t time.Time) (bool, error)
-	AllBookingsPaginated(ctx context.Context, afterTime time.Time, afterUuid string, limit int) ([]Booking, error)
+	SearchFlights(ctx context.Context, filter FlightFilter) ([]FlightSummary, error)
+	// CreateWaitlistEntry queues the passenger, ErrAlreadyExists when they are waiting for the launchpad and date already.
+	CreateWaitlistEntry(ctx context.Context, e WaitlistEntry) (WaitlistEntry, error)
+	GetWaitlistEntryById(ctx context.Context, id string) (WaitlistEntry, error)
+	WaitingEntries(ctx context.Context, launchpadId string, date time.Time) ([]WaitlistEntry, error)
+	// PromoteWaitlistEntry books the passenger of a waiting entry like CreateBooking and marks the entry promoted.
+	PromoteWaitlistEntry(ctx context.Context, id string, u User, f Flight, price int64, payBy *time.Time) (Booking, error)
+	// MarkWaitlistEntryStale stops a waiting entry from being promoted.
+	MarkWaitlistEntryStale(ctx context.Context, id string) error
+	// IsBookedOn tells whether the passenger has a booking taking a seat on the flight of the launchpad and date.
+	// When the user has no ID the passenger is matched on name, gender and birthday.
+	IsBookedOn(ctx context.Context, u User, launchpadId string, date time.Time) (bool, error)
+	// GetLaunchPadWeekAvailability tells whether no flight but except, uuid.Nil for none, flies from the launchpad
+	// to the destination in the week of t.
+	GetLaunchPadWeekAvailability(ctx context.Context, launchpadId, destinationId string, t time.Time, except uuid.UUID) (bool, error)
+	AllBookingsPaginated(ctx context.Context, filter BookingFilter) ([]Booking, error)
+	CountBookings(ctx context.Context, filter BookingFilter, exact bool) (int64, error)
+	StreamBookings(ctx context.Context, filter BookingFilter, fn func(Booking) error) error
 }
 
 type Destination struct {
@@ -66,6 +181,8 @@ type Flight struct {
 	LaunchpadID string
 	Destination Destination
 	Date        time.Time
+	Status      string
+	Capacity    int
 }
 
 func (o Flight) MarshalJSON() ([]byte, error) {
@@ -83,10 +200,147 @@ func (o *Flight) IsIDEmpty() bool {
 	return o.ID == uuid.Nil
 }
 
+// FlightFilter narrows down SearchFlights. Zero values are ignored.
+// Results are ordered by launch date and id and start after AfterDate, AfterID when set.
+// HasActiveBookings only counts the active bookings, not the held or pending payment ones.
+type FlightFilter struct {
+	LaunchpadID       string
+	DestinationID     string
+	Status            string
+	From              time.Time
+	To                time.Time
+	HasActiveBookings *bool
+	AfterDate         time.Time
+	AfterID           string
+	Limit             int
+}
+
+const (
+	BookingSortCreatedAt  = "created_at"
+	BookingSortLaunchDate = "launch_date"
+)
+
+// BookingFilter narrows down AllBookingsPaginated. Zero values are ignored.
+// Results are ordered by SortBy, created_at when empty, then id, descending when Desc is set,
+// and start after the booking whose sort key is AfterValue, AfterID when set.
+// PassengerName matches a part of the first or last name, ignoring case.
+type BookingFilter struct {
+	UserID        string
+	Status        string
+	DestinationID string
+	LaunchpadID   string
+	LaunchFrom    time.Time
+	LaunchTo      time.Time
+	PassengerName string
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	SortBy        string
+	Desc          bool
+	AfterValue    time.Time
+	AfterID       string
+	Limit         int
+}
+
+// FlightSummary is a flight with the number of passengers taking a seat on it,
+// whether their booking is active, held or pending payment.
+type FlightSummary struct {
+	Flight     Flight
+	Passengers int
+}
+
 type Booking struct {
-	ID        uuid.UUID
-	User      User
-	Flight    Flight
-	Status    string
-	CreatedAt time.Time
+	ID           uuid.UUID
+	User         User
+	Flight       Flight
+	Status       string
+	CreatedAt    time.Time
+	CancelledAt  *time.Time `json:",omitempty"`
+	CancelReason string     `json:",omitempty"`
+	// ExpiresAt is set while the booking is held or pending payment
+	ExpiresAt *time.Time `json:",omitempty"`
+	// Price is in cents
+	Price int64
+}
+
+// BookingEvent is a change of a booking. FlightID is the flight of the booking after the change.
+type BookingEvent struct {
+	ID               int64
+	BookingID        uuid.UUID
+	Kind             string
+	FromStatus       string `json:",omitempty"`
+	ToStatus         string
+	FlightID         uuid.UUID
+	PreviousFlightID *uuid.UUID `json:",omitempty"`
+	Details          string     `json:",omitempty"`
+	Actor            string
+	CreatedAt        time.Time
+}
+
+// Payment is a capture or a refund of a booking with the payment provider.
+// Amount is in cents of Currency. The ProviderRef of a pending refund is the capture to refund.
+type Payment struct {
+	ID          uuid.UUID
+	BookingID   uuid.UUID
+	Kind        string
+	Amount      int64
+	Currency    string
+	ProviderRef string
+	CreatedAt   time.Time
+}
+
+// SpaceXLaunchpad is a launchpad synced from the SpaceX API.
+type SpaceXLaunchpad struct {
+	ID     string
+	Status string
+}
+
+// SpaceXLaunch is an upcoming launch synced from the SpaceX API, taking its launchpad
+// from Date for the DatePrecision of the API, e.g. "day" or "quarter".
+type SpaceXLaunch struct {
+	ID            string
+	LaunchpadID   string
+	Date          time.Time
+	DatePrecision string
+}
+
+// WaitlistEntry is a passenger waiting for a seat. Once promoted BookingID
+// references the booking made for the passenger.
+type WaitlistEntry struct {
+	ID          uuid.UUID
+	FirstName   string
+	LastName    string
+	Gender      string
+	Birthday    time.Time
+	LaunchpadID string
+	Destination Destination
+	Date        time.Time
+	Status      string
+	BookingID   *uuid.UUID `json:",omitempty"`
+	CreatedAt   time.Time
+	PromotedAt  *time.Time `json:",omitempty"`
+}
+
+func (o WaitlistEntry) MarshalJSON() ([]byte, error) {
+	type Alias WaitlistEntry
+	return json.Marshal(&struct {
+		Birthday string
+		Gender   string
+		Date     string
+		Alias
+	}{
+		Birthday: o.Birthday.Format("2006-01-02"),
+		Gender:   genderFull(o.Gender),
+		Date:     o.Date.Format("2006-01-02"),
+		Alias:    (Alias)(o),
+	})
+}
+
+// IdempotencyRecord is the stored outcome of a request made with an Idempotency-Key.
+// StatusCode is 0 while the original request is still in progress.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	StatusCode  int
+	Body        []byte
+	CreatedAt   time.Time
 }
@@ -0,0 +1,87 @@
+package flight
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"spacetrouble/pkg/apiutils"
+)
+
+func FlightHandler(srv FlightService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			all(srv, w, r)
+		}
+	}
+}
+
+func all(srv FlightService, w http.ResponseWriter, r *http.Request) {
+	listReq, err := parseListFlightsReq(r.URL.Query())
+	if err != nil {
+		ae := apiutils.NewBadRequest(err.Error())
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	if err := listReq.Validate(); err != nil {
+		ae := apiutils.NewBadRequest(err.Error())
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	if listReq.Limit == 0 {
+		listReq.Limit = 10
+	}
+
+	ans, err := srv.AllFlights(r.Context(), listReq)
+	if err != nil {
+		ae := getApiError(err)
+		apiutils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	apiutils.RenderResponse(r, w, http.StatusOK, ans)
+}
+
+func parseListFlightsReq(query url.Values) (ListFlightsReq, error) {
+	var err error
+	ans := ListFlightsReq{
+		LaunchpadID:   query.Get("launchpad"),
+		DestinationID: query.Get("destination"),
+		Status:        query.Get("status"),
+		Cursor:        query.Get("cursor"),
+	}
+	if v := query.Get("limit"); v != "" {
+		if ans.Limit, err = strconv.Atoi(v); err != nil {
+			return ans, err
+		}
+	}
+	if v := query.Get("from"); v != "" {
+		if ans.From, err = time.Parse(dateLayoutFmt, v); err != nil {
+			return ans, err
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if ans.To, err = time.Parse(dateLayoutFmt, v); err != nil {
+			return ans, err
+		}
+	}
+	if v := query.Get("has_active_bookings"); v != "" {
+		hasActive, err := strconv.ParseBool(v)
+		if err != nil {
+			return ans, err
+		}
+		ans.HasActiveBookings = &hasActive
+	}
+	return ans, nil
+}
+
+func getApiError(err error) apiutils.ApiError {
+	ae := apiutils.ApiError{Msg: err.Error()}
+	switch err {
+	case ErrInvalidCursor:
+		ae.StatusCode = http.StatusBadRequest
+	default:
+		ae.StatusCode = http.StatusInternalServerError
+	}
+	return ae
+}
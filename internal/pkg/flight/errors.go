@@ -0,0 +1,9 @@
+package flight
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
@@ -0,0 +1,101 @@
+package flight
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+const (
+	dateLayoutFmt = "2006-01-02"
+)
+
+type ListFlightsReq struct {
+	LaunchpadID       string
+	DestinationID     string
+	Status            string
+	From              time.Time
+	To                time.Time
+	HasActiveBookings *bool
+	Limit             int
+	Cursor            string
+}
+
+func (o *ListFlightsReq) Validate() error {
+	if o.LaunchpadID != "" && len(o.LaunchpadID) != 24 {
+		return errors.New("launchpad must have length 24")
+	}
+	if o.DestinationID != "" {
+		if _, err := uuid.Parse(o.DestinationID); err != nil {
+			return errors.New("invalid uuid for destination")
+		}
+	}
+	if o.Status != "" && o.Status != entity.FlightStatusScheduled && o.Status != entity.FlightStatusCancelled {
+		return errors.New("invalid status")
+	}
+	if !o.From.IsZero() && !o.To.IsZero() && o.To.Before(o.From) {
+		return errors.New("to is before from")
+	}
+	if o.Limit < 0 {
+		return errors.New("negative limit")
+	}
+	return nil
+}
+
+type FlightResponse struct {
+	entity.Flight
+	Passengers int
+}
+
+func (o FlightResponse) MarshalJSON() ([]byte, error) {
+	type Alias entity.Flight
+	return json.Marshal(&struct {
+		Date       string
+		Passengers int
+		Alias
+	}{
+		Date:       o.Date.Format(dateLayoutFmt),
+		Passengers: o.Passengers,
+		Alias:      (Alias)(o.Flight),
+	})
+}
+
+type AllFlightsResponse struct {
+	Flights []FlightResponse `json:"flights"`
+	Limit   int              `json:"limit"`
+	Cursor  string           `json:"cursor"`
+}
+
+func decodeCursor(encoded string) (date time.Time, id string, err error) {
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return
+	}
+	arr := strings.Split(string(b), ",")
+	if len(arr) != 2 {
+		err = ErrInvalidCursor
+		return
+	}
+	date, err = time.Parse(dateLayoutFmt, arr[0])
+	if err != nil {
+		return
+	}
+	if _, err = uuid.Parse(arr[1]); err != nil {
+		err = ErrInvalidCursor
+		return
+	}
+	id = arr[1]
+	return
+}
+
+func encodeCursor(date time.Time, id string) string {
+	key := fmt.Sprintf("%s,%s", date.Format(dateLayoutFmt), id)
+	return base64.StdEncoding.EncodeToString([]byte(key))
+}
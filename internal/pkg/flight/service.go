@@ -0,0 +1,60 @@
+package flight
+
+import (
+	"context"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+type FlightService interface {
+	AllFlights(ctx context.Context, req ListFlightsReq) (AllFlightsResponse, error)
+}
+
+type flightSrv struct {
+	store entity.Store
+}
+
+func NewFlightService(store entity.Store) *flightSrv {
+	ans := flightSrv{
+		store: store,
+	}
+	return &ans
+}
+
+func (o *flightSrv) AllFlights(ctx context.Context, req ListFlightsReq) (AllFlightsResponse, error) {
+	ans := AllFlightsResponse{
+		Flights: make([]FlightResponse, 0),
+		Limit:   req.Limit,
+	}
+	filter := entity.FlightFilter{
+		LaunchpadID:       req.LaunchpadID,
+		DestinationID:     req.DestinationID,
+		Status:            req.Status,
+		From:              req.From,
+		To:                req.To,
+		HasActiveBookings: req.HasActiveBookings,
+		Limit:             req.Limit,
+	}
+	if req.Cursor != "" {
+		var err error
+		filter.AfterDate, filter.AfterID, err = decodeCursor(req.Cursor)
+		if err != nil {
+			return ans, ErrInvalidCursor
+		}
+	}
+	flights, err := o.store.SearchFlights(ctx, filter)
+	if err != nil {
+		return ans, err
+	}
+	for i := range flights {
+		ans.Flights = append(ans.Flights, FlightResponse{
+			Flight:     flights[i].Flight,
+			Passengers: flights[i].Passengers,
+		})
+	}
+	if len(ans.Flights) > 0 {
+		last := ans.Flights[len(ans.Flights)-1]
+		ans.Cursor = encodeCursor(last.Date, last.ID.String())
+	}
+	return ans, nil
+}
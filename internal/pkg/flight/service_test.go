@@ -0,0 +1,204 @@
+package flight
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"spacetrouble/internal/pkg/data/postgres"
+	"spacetrouble/internal/pkg/entity"
+
+	"spacetrouble/pkg/testutils"
+)
+
+func TestMain(m *testing.M) {
+	workingDir, _ := os.Getwd()
+	rootDir := strings.Replace(workingDir, "internal/pkg/flight", "", 1)
+
+	ctx := context.Background()
+	postgresContainer := testutils.SpinPostgresContainer(ctx, rootDir)
+
+	defer postgresContainer.Terminate(ctx)
+
+	exitCode := m.Run()
+
+	os.Exit(exitCode)
+}
+
+func getStoreAndDb() (entity.Store, *pgxpool.Pool, error) {
+	db, err := testutils.GetTestDb()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := db.Ping(context.Background()); err != nil {
+		return nil, nil, err
+	}
+	store := postgres.NewStore(db)
+	return store, db, nil
+}
+
+// cleanDatabase drops the database of the test.
+func cleanDatabase(db *pgxpool.Pool) {
+	if err := testutils.DropTestDb(db); err != nil {
+		panic(err)
+	}
+}
+
+func genLaunchId() string {
+	return strings.Replace(uuid.New().String(), "-", "", -1)[:24]
+}
+
+func createBooking(store entity.Store, f entity.Flight, firstName string) (entity.Booking, error) {
+	user := entity.User{
+		FirstName: firstName,
+		LastName:  "Papadopoulos",
+		Gender:    "m",
+		Birthday:  time.Date(1923, 11, 13, 0, 0, 0, 0, time.UTC),
+	}
+	return store.CreateBooking(context.Background(), user, f, 0, nil)
+}
+
+func TestAllFlightsFiltersAndPassengers(t *testing.T) {
+	store, db, err := getStoreAndDb()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	defer cleanDatabase(db)
+
+	destinations, err := store.GetAllDestinations(context.Background())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	launchpad := genLaunchId()
+	full, err := createBooking(store, entity.Flight{
+		LaunchpadID: launchpad,
+		Destination: destinations[0],
+		Date:        time.Date(2049, 4, 6, 0, 0, 0, 0, time.UTC),
+		Capacity:    10,
+	}, "Giorgos")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := createBooking(store, full.Flight, "John"); err != nil {
+		t.Error(err)
+		return
+	}
+	empty, err := createBooking(store, entity.Flight{
+		LaunchpadID: launchpad,
+		Destination: destinations[1],
+		Date:        time.Date(2049, 5, 6, 0, 0, 0, 0, time.UTC),
+		Capacity:    10,
+	}, "Giorgos")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := store.CancelBooking(context.Background(), empty.ID.String(), ""); err != nil {
+		t.Error(err)
+		return
+	}
+	payBy := time.Now().Add(time.Hour)
+	pending, err := store.CreateBooking(context.Background(), entity.User{
+		FirstName: "Maria",
+		LastName:  "Papadopoulou",
+		Gender:    "f",
+		Birthday:  time.Date(1925, 2, 3, 0, 0, 0, 0, time.UTC),
+	}, entity.Flight{
+		LaunchpadID: launchpad,
+		Destination: destinations[2],
+		Date:        time.Date(2049, 6, 6, 0, 0, 0, 0, time.UTC),
+		Capacity:    10,
+	}, 0, &payBy)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	srv := NewFlightService(store)
+
+	ans, err := srv.AllFlights(context.Background(), ListFlightsReq{LaunchpadID: launchpad, Limit: 10})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(ans.Flights) != 3 {
+		t.Errorf("expected 3 flights but got %d", len(ans.Flights))
+		return
+	}
+	if ans.Flights[0].ID != full.Flight.ID || ans.Flights[0].Passengers != 2 {
+		t.Errorf("expected first flight with 2 passengers but got %+v", ans.Flights[0])
+		return
+	}
+	if ans.Flights[1].ID != empty.Flight.ID || ans.Flights[1].Passengers != 0 {
+		t.Errorf("expected second flight without passengers but got %+v", ans.Flights[1])
+		return
+	}
+	if ans.Flights[2].ID != pending.Flight.ID || ans.Flights[2].Passengers != 1 {
+		t.Errorf("expected third flight with 1 passenger but got %+v", ans.Flights[2])
+		return
+	}
+
+	hasActive := true
+	ans, err = srv.AllFlights(context.Background(), ListFlightsReq{
+		LaunchpadID:       launchpad,
+		HasActiveBookings: &hasActive,
+		From:              time.Date(2049, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:                time.Date(2049, 12, 31, 0, 0, 0, 0, time.UTC),
+		Limit:             10,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(ans.Flights) != 1 || ans.Flights[0].ID != full.Flight.ID {
+		t.Errorf("expected only the flight with active bookings but got %+v", ans.Flights)
+		return
+	}
+
+	hasActive = false
+	ans, err = srv.AllFlights(context.Background(), ListFlightsReq{
+		LaunchpadID:       launchpad,
+		HasActiveBookings: &hasActive,
+		Limit:             10,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(ans.Flights) != 2 || ans.Flights[0].ID != empty.Flight.ID || ans.Flights[1].ID != pending.Flight.ID {
+		t.Errorf("expected the flights without active bookings but got %+v", ans.Flights)
+		return
+	}
+
+	ans, err = srv.AllFlights(context.Background(), ListFlightsReq{LaunchpadID: launchpad, Limit: 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ans, err = srv.AllFlights(context.Background(), ListFlightsReq{LaunchpadID: launchpad, Limit: 1, Cursor: ans.Cursor})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(ans.Flights) != 1 || ans.Flights[0].ID != empty.Flight.ID {
+		t.Errorf("expected the second page to have the second flight but got %+v", ans.Flights)
+		return
+	}
+
+	badCursor := base64.StdEncoding.EncodeToString([]byte("2049-04-06,not-a-uuid"))
+	_, err = srv.AllFlights(context.Background(), ListFlightsReq{LaunchpadID: launchpad, Limit: 1, Cursor: badCursor})
+	if err != ErrInvalidCursor {
+		t.Errorf("expected %v but got %v", ErrInvalidCursor, err)
+	}
+}
@@ -0,0 +1,64 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDeclined       = errors.New("payment declined")
+	ErrUnknownPayment = errors.New("payment does not exist")
+	ErrRefundTooLarge = errors.New("refund exceeds the captured amount")
+)
+
+// FakeProvider is an in-process payment provider. Every capture succeeds
+// unless its amount is above DeclineAbove, when set.
+type FakeProvider struct {
+	DeclineAbove int64
+
+	lock     sync.Mutex
+	captured map[string]int64
+	refunded map[string]int64
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		captured: make(map[string]int64),
+		refunded: make(map[string]int64),
+	}
+}
+
+func (o *FakeProvider) Capture(ctx context.Context, bookingID string, amount int64, currency string) (string, error) {
+	if o.DeclineAbove > 0 && amount > o.DeclineAbove {
+		return "", ErrDeclined
+	}
+	ref := "fake_ch_" + uuid.New().String()
+	o.lock.Lock()
+	o.captured[ref] = amount
+	o.lock.Unlock()
+	return ref, nil
+}
+
+func (o *FakeProvider) Refund(ctx context.Context, captureRef string, amount int64) (string, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	captured, ok := o.captured[captureRef]
+	if !ok {
+		return "", ErrUnknownPayment
+	}
+	if o.refunded[captureRef]+amount > captured {
+		return "", ErrRefundTooLarge
+	}
+	o.refunded[captureRef] += amount
+	return "fake_re_" + uuid.New().String(), nil
+}
+
+// Refunded returns the amount refunded so far for a capture.
+func (o *FakeProvider) Refunded(captureRef string) int64 {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.refunded[captureRef]
+}
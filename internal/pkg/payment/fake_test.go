@@ -0,0 +1,37 @@
+package payment
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFakeProvider(t *testing.T) {
+	p := NewFakeProvider()
+	p.DeclineAbove = 1000
+
+	if _, err := p.Capture(context.Background(), "b1", 1001, "USD"); err != ErrDeclined {
+		t.Errorf("expected %v but got %v", ErrDeclined, err)
+		return
+	}
+	ref, err := p.Capture(context.Background(), "b1", 1000, "USD")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := p.Refund(context.Background(), ref, 600); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := p.Refund(context.Background(), ref, 600); err != ErrRefundTooLarge {
+		t.Errorf("expected %v but got %v", ErrRefundTooLarge, err)
+		return
+	}
+	if got := p.Refunded(ref); got != 600 {
+		t.Errorf("expected %v but got %v", 600, got)
+		return
+	}
+	if _, err := p.Refund(context.Background(), "unknown", 1); err != ErrUnknownPayment {
+		t.Errorf("expected %v but got %v", ErrUnknownPayment, err)
+		return
+	}
+}
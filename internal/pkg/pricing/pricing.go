@@ -0,0 +1,127 @@
+package pricing
+
+import (
+	"time"
+)
+
+const (
+	Currency = "USD"
+
+	// DefaultBaseFare is the fare in cents of a destination without a specific one.
+	DefaultBaseFare = 100000
+)
+
+// Policy computes fares. Prices are in cents of Currency.
+// A destination specific base fare wins over the default one.
+type Policy struct {
+	BaseFare      int64
+	ByDestination map[string]int64
+}
+
+// Input is what the fare depends on.
+type Input struct {
+	DestinationID string
+	LaunchDate    time.Time
+	Birthday      time.Time
+	// SeatsTaken and Capacity give the load of the flight before booking.
+	SeatsTaken int
+	Capacity   int
+}
+
+// Adjustment is a percentage added to, or removed from when negative, the base fare.
+type Adjustment struct {
+	Reason  string
+	Percent int
+}
+
+type Quote struct {
+	BaseFare    int64
+	Adjustments []Adjustment
+	Price       int64
+	Currency    string
+}
+
+// Quote computes the fare at the time now. Adjustments add up, so a child flying
+// on short notice pays the base fare -50% +20%.
+func (o Policy) Quote(in Input, now time.Time) Quote {
+	ans := Quote{
+		BaseFare:    o.baseFare(in.DestinationID),
+		Adjustments: make([]Adjustment, 0),
+		Currency:    Currency,
+	}
+	if a, ok := launchAdjustment(in.LaunchDate.Sub(now)); ok {
+		ans.Adjustments = append(ans.Adjustments, a)
+	}
+	if a, ok := ageAdjustment(age(in.Birthday, in.LaunchDate)); ok {
+		ans.Adjustments = append(ans.Adjustments, a)
+	}
+	if a, ok := loadAdjustment(in.SeatsTaken, in.Capacity); ok {
+		ans.Adjustments = append(ans.Adjustments, a)
+	}
+	percent := 100
+	for _, a := range ans.Adjustments {
+		percent += a.Percent
+	}
+	if percent < 0 {
+		percent = 0
+	}
+	ans.Price = ans.BaseFare * int64(percent) / 100
+	return ans
+}
+
+func (o Policy) baseFare(destinationID string) int64 {
+	if f, ok := o.ByDestination[destinationID]; ok && f > 0 {
+		return f
+	}
+	if o.BaseFare > 0 {
+		return o.BaseFare
+	}
+	return DefaultBaseFare
+}
+
+const day = 24 * time.Hour
+
+func launchAdjustment(untilLaunch time.Duration) (Adjustment, bool) {
+	switch {
+	case untilLaunch < 7*day:
+		return Adjustment{Reason: "last minute", Percent: 50}, true
+	case untilLaunch < 30*day:
+		return Adjustment{Reason: "short notice", Percent: 20}, true
+	case untilLaunch >= 180*day:
+		return Adjustment{Reason: "early booking", Percent: -15}, true
+	}
+	return Adjustment{}, false
+}
+
+func ageAdjustment(years int) (Adjustment, bool) {
+	switch {
+	case years < 12:
+		return Adjustment{Reason: "child", Percent: -50}, true
+	case years >= 65:
+		return Adjustment{Reason: "senior", Percent: -30}, true
+	}
+	return Adjustment{}, false
+}
+
+func loadAdjustment(seatsTaken, capacity int) (Adjustment, bool) {
+	if capacity <= 0 {
+		return Adjustment{}, false
+	}
+	load := seatsTaken * 100 / capacity
+	switch {
+	case load >= 90:
+		return Adjustment{Reason: "last seats", Percent: 40}, true
+	case load >= 50:
+		return Adjustment{Reason: "high demand", Percent: 15}, true
+	}
+	return Adjustment{}, false
+}
+
+// age returns the age in years of a passenger born on birthday at the time t.
+func age(birthday, t time.Time) int {
+	years := t.Year() - birthday.Year()
+	if t.Month() < birthday.Month() || (t.Month() == birthday.Month() && t.Day() < birthday.Day()) {
+		years--
+	}
+	return years
+}
@@ -0,0 +1,83 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuote(t *testing.T) {
+	now := time.Date(2049, 1, 1, 12, 0, 0, 0, time.UTC)
+	policy := Policy{
+		BaseFare:      100000,
+		ByDestination: map[string]int64{"mars": 200000},
+	}
+	adult := time.Date(1990, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		in       Input
+		expected int64
+	}{
+		{
+			name:     "base fare",
+			in:       Input{LaunchDate: now.AddDate(0, 2, 0), Birthday: adult, Capacity: 10},
+			expected: 100000,
+		},
+		{
+			name:     "destination fare",
+			in:       Input{DestinationID: "mars", LaunchDate: now.AddDate(0, 2, 0), Birthday: adult, Capacity: 10},
+			expected: 200000,
+		},
+		{
+			name:     "last minute",
+			in:       Input{LaunchDate: now.AddDate(0, 0, 3), Birthday: adult, Capacity: 10},
+			expected: 150000,
+		},
+		{
+			name:     "early booking",
+			in:       Input{LaunchDate: now.AddDate(1, 0, 0), Birthday: adult, Capacity: 10},
+			expected: 85000,
+		},
+		{
+			name:     "child on short notice",
+			in:       Input{LaunchDate: now.AddDate(0, 0, 10), Birthday: now.AddDate(-5, 0, 0), Capacity: 10},
+			expected: 70000,
+		},
+		{
+			name:     "senior",
+			in:       Input{LaunchDate: now.AddDate(0, 2, 0), Birthday: now.AddDate(-70, 0, 0), Capacity: 10},
+			expected: 70000,
+		},
+		{
+			name:     "high demand",
+			in:       Input{LaunchDate: now.AddDate(0, 2, 0), Birthday: adult, SeatsTaken: 5, Capacity: 10},
+			expected: 115000,
+		},
+		{
+			name:     "last seats",
+			in:       Input{LaunchDate: now.AddDate(0, 2, 0), Birthday: adult, SeatsTaken: 9, Capacity: 10},
+			expected: 140000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := policy.Quote(tt.in, now)
+			if q.Price != tt.expected {
+				t.Errorf("expected %v but got %v (%+v)", tt.expected, q.Price, q.Adjustments)
+			}
+			if q.Currency != Currency {
+				t.Errorf("expected %v but got %v", Currency, q.Currency)
+			}
+		})
+	}
+}
+
+func TestAge(t *testing.T) {
+	birthday := time.Date(2000, 3, 15, 0, 0, 0, 0, time.UTC)
+	if a := age(birthday, time.Date(2020, 3, 14, 0, 0, 0, 0, time.UTC)); a != 19 {
+		t.Errorf("expected %v but got %v", 19, a)
+	}
+	if a := age(birthday, time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC)); a != 20 {
+		t.Errorf("expected %v but got %v", 20, a)
+	}
+}
@@ -0,0 +1,201 @@
+package spacex
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// API is the part of the SpaceX API the bookings depend on.
+type API interface {
+	GetLaunchPadById(ctx context.Context, launchpadID string) (LaunchPad, error)
+	QueryUpcomingLaunchesLaunchPad(ctx context.Context, launchpadID string) ([]Launch, error)
+}
+
+// CacheStore persists the cache entries so that they outlive the process and are shared
+// by the instances. GetSpaceXCacheEntry returns entity.ErrNotFound for a missing entry.
+type CacheStore interface {
+	GetSpaceXCacheEntry(ctx context.Context, key string) ([]byte, time.Time, error)
+	PutSpaceXCacheEntry(ctx context.Context, key string, value []byte, storedAt time.Time) error
+}
+
+// CacheOptions configures CachedClient. Entries are fresh for the TTL of their resource,
+// after that they are fetched again, and still served for MaxStale when the API fails.
+type CacheOptions struct {
+	Size         int
+	LaunchpadTTL time.Duration
+	LaunchesTTL  time.Duration
+	MaxStale     time.Duration
+	// FetchTimeout bounds a call to the API, which does not depend on the context of the caller
+	// that started it since other callers wait for it too. 30s when not set.
+	FetchTimeout time.Duration
+	// Store, when set, persists the entries
+	Store CacheStore
+}
+
+// CachedClient decorates an API with an in-memory LRU cache, and optionally a persistent one,
+// concurrent misses of an entry wait for a single call to the API.
+type CachedClient struct {
+	api  API
+	opts CacheOptions
+	now  func() time.Time
+
+	lock     sync.Mutex
+	lru      *list.List
+	entries  map[string]*list.Element
+	inflight map[string]*cacheCall
+}
+
+type cacheEntry struct {
+	key      string
+	value    []byte
+	storedAt time.Time
+}
+
+type cacheCall struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+func NewCachedClient(api API, opts CacheOptions) *CachedClient {
+	if opts.Size <= 0 {
+		opts.Size = 1000
+	}
+	if opts.FetchTimeout <= 0 {
+		opts.FetchTimeout = 30 * time.Second
+	}
+	return &CachedClient{
+		api:      api,
+		opts:     opts,
+		now:      time.Now,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		inflight: make(map[string]*cacheCall),
+	}
+}
+
+func (o *CachedClient) IsLaunchpadAvailable(ctx context.Context, launchpadID string, ts time.Time) (bool, error) {
+	return isLaunchpadAvailable(ctx, o, launchpadID, ts)
+}
+
+func (o *CachedClient) GetLaunchPadById(ctx context.Context, launchpadID string) (LaunchPad, error) {
+	var ans LaunchPad
+	b, err := o.get(ctx, "launchpad:"+launchpadID, o.opts.LaunchpadTTL, func(ctx context.Context) (interface{}, error) {
+		return o.api.GetLaunchPadById(ctx, launchpadID)
+	})
+	if err != nil {
+		return ans, err
+	}
+	return ans, json.Unmarshal(b, &ans)
+}
+
+func (o *CachedClient) QueryUpcomingLaunchesLaunchPad(ctx context.Context, launchpadID string) ([]Launch, error) {
+	var ans []Launch
+	b, err := o.get(ctx, "launches:"+launchpadID, o.opts.LaunchesTTL, func(ctx context.Context) (interface{}, error) {
+		return o.api.QueryUpcomingLaunchesLaunchPad(ctx, launchpadID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ans, json.Unmarshal(b, &ans)
+}
+
+// get returns the entry of key when fresh, or calls fetch. Errors of the API are not cached,
+// a stale entry is returned instead when there is one. The call runs in the background, a caller
+// giving up does not fail it for the others waiting.
+func (o *CachedClient) get(ctx context.Context, key string, ttl time.Duration, fetch func(context.Context) (interface{}, error)) ([]byte, error) {
+	e, ok := o.lookup(ctx, key)
+	if ok && o.now().Sub(e.storedAt) < ttl {
+		return e.value, nil
+	}
+
+	o.lock.Lock()
+	call, found := o.inflight[key]
+	if !found {
+		call = &cacheCall{done: make(chan struct{})}
+		o.inflight[key] = call
+	}
+	o.lock.Unlock()
+
+	if !found {
+		go func() {
+			fctx, cancel := context.WithTimeout(context.Background(), o.opts.FetchTimeout)
+			defer cancel()
+			call.value, call.err = o.fetch(fctx, key, fetch)
+			o.lock.Lock()
+			delete(o.inflight, key)
+			o.lock.Unlock()
+			close(call.done)
+		}()
+	}
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if call.err != nil && !errors.Is(call.err, ErrNotFound) && ok && o.now().Sub(e.storedAt) < ttl+o.opts.MaxStale {
+		return e.value, nil
+	}
+	return call.value, call.err
+}
+
+func (o *CachedClient) fetch(ctx context.Context, key string, fetch func(context.Context) (interface{}, error)) ([]byte, error) {
+	v, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	e := cacheEntry{key: key, value: b, storedAt: o.now()}
+	o.put(e)
+	if o.opts.Store != nil {
+		// the API answered, failing to persist the answer only costs a call later
+		_ = o.opts.Store.PutSpaceXCacheEntry(ctx, key, b, e.storedAt)
+	}
+	return b, nil
+}
+
+// lookup returns the entry from memory or else from the store, which fills the memory.
+func (o *CachedClient) lookup(ctx context.Context, key string) (cacheEntry, bool) {
+	o.lock.Lock()
+	if el, ok := o.entries[key]; ok {
+		o.lru.MoveToFront(el)
+		e := el.Value.(cacheEntry)
+		o.lock.Unlock()
+		return e, true
+	}
+	o.lock.Unlock()
+	if o.opts.Store == nil {
+		return cacheEntry{}, false
+	}
+	value, storedAt, err := o.opts.Store.GetSpaceXCacheEntry(ctx, key)
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	e := cacheEntry{key: key, value: value, storedAt: storedAt}
+	o.put(e)
+	return e, true
+}
+
+func (o *CachedClient) put(e cacheEntry) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if el, ok := o.entries[e.key]; ok {
+		el.Value = e
+		o.lru.MoveToFront(el)
+		return
+	}
+	o.entries[e.key] = o.lru.PushFront(e)
+	for o.lru.Len() > o.opts.Size {
+		oldest := o.lru.Back()
+		o.lru.Remove(oldest)
+		delete(o.entries, oldest.Value.(cacheEntry).key)
+	}
+}
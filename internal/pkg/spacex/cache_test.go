@@ -0,0 +1,223 @@
+package spacex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingAPI struct {
+	calls   int32
+	err     error
+	release chan struct{}
+}
+
+func (o *countingAPI) GetLaunchPadById(ctx context.Context, launchpadID string) (LaunchPad, error) {
+	atomic.AddInt32(&o.calls, 1)
+	if o.release != nil {
+		<-o.release
+	}
+	if o.err != nil {
+		return LaunchPad{}, o.err
+	}
+	if err := ctx.Err(); err != nil {
+		return LaunchPad{}, err
+	}
+	return LaunchPad{Id: launchpadID, Status: "active"}, nil
+}
+
+func (o *countingAPI) QueryUpcomingLaunchesLaunchPad(ctx context.Context, launchpadID string) ([]Launch, error) {
+	atomic.AddInt32(&o.calls, 1)
+	if o.err != nil {
+		return nil, o.err
+	}
+	return []Launch{{LaunchPadID: launchpadID, Date: 2524608000, DatePrecision: "day"}}, nil
+}
+
+type memCacheStore struct {
+	lock    sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func (o *memCacheStore) GetSpaceXCacheEntry(ctx context.Context, key string) ([]byte, time.Time, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	e, ok := o.entries[key]
+	if !ok {
+		return nil, time.Time{}, errors.New("not found")
+	}
+	return e.value, e.storedAt, nil
+}
+
+func (o *memCacheStore) PutSpaceXCacheEntry(ctx context.Context, key string, value []byte, storedAt time.Time) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.entries[key] = cacheEntry{key: key, value: value, storedAt: storedAt}
+	return nil
+}
+
+func newTestCachedClient(api API, opts CacheOptions) (*CachedClient, *time.Time) {
+	now := time.Date(2049, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewCachedClient(api, opts)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCachedClientTTLAndStale(t *testing.T) {
+	api := &countingAPI{}
+	c, now := newTestCachedClient(api, CacheOptions{LaunchpadTTL: time.Hour, MaxStale: time.Hour})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		lp, err := c.GetLaunchPadById(ctx, "lp1")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if lp.Id != "lp1" {
+			t.Errorf("expected %v but got %v", "lp1", lp.Id)
+			return
+		}
+	}
+	if api.calls != 1 {
+		t.Errorf("expected %v but got %v", 1, api.calls)
+		return
+	}
+
+	*now = now.Add(90 * time.Minute)
+	api.err = ErrBadStatusCode
+	if _, err := c.GetLaunchPadById(ctx, "lp1"); err != nil {
+		t.Errorf("expected the stale launchpad but got %v", err)
+		return
+	}
+	*now = now.Add(time.Hour)
+	if _, err := c.GetLaunchPadById(ctx, "lp1"); err != ErrBadStatusCode {
+		t.Errorf("expected %v but got %v", ErrBadStatusCode, err)
+		return
+	}
+
+	api.err = nil
+	if _, err := c.GetLaunchPadById(ctx, "lp1"); err != nil {
+		t.Error(err)
+		return
+	}
+	if api.calls != 4 {
+		t.Errorf("expected %v but got %v", 4, api.calls)
+	}
+}
+
+func TestCachedClientEviction(t *testing.T) {
+	api := &countingAPI{}
+	c, _ := newTestCachedClient(api, CacheOptions{Size: 1, LaunchpadTTL: time.Hour})
+	ctx := context.Background()
+
+	for _, id := range []string{"lp1", "lp2", "lp1"} {
+		if _, err := c.GetLaunchPadById(ctx, id); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if api.calls != 3 {
+		t.Errorf("expected %v but got %v", 3, api.calls)
+	}
+}
+
+func TestCachedClientConcurrentMisses(t *testing.T) {
+	api := &countingAPI{release: make(chan struct{})}
+	c, _ := newTestCachedClient(api, CacheOptions{LaunchpadTTL: time.Hour})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.GetLaunchPadById(context.Background(), "lp1")
+			errs <- err
+		}()
+	}
+	// let the callers pile up behind the first one
+	for atomic.LoadInt32(&api.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(api.release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if api.calls != 1 {
+		t.Errorf("expected %v but got %v", 1, api.calls)
+	}
+}
+
+func TestCachedClientCallerGivesUp(t *testing.T) {
+	api := &countingAPI{release: make(chan struct{})}
+	c, _ := newTestCachedClient(api, CacheOptions{LaunchpadTTL: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetLaunchPadById(ctx, "lp1")
+		first <- err
+	}()
+	for atomic.LoadInt32(&api.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := make(chan error, 1)
+	go func() {
+		_, err := c.GetLaunchPadById(context.Background(), "lp1")
+		second <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the first caller leaves, the call goes on for the second one
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Errorf("expected %v but got %v", context.Canceled, err)
+		return
+	}
+	close(api.release)
+	if err := <-second; err != nil {
+		t.Error(err)
+		return
+	}
+	if api.calls != 1 {
+		t.Errorf("expected %v but got %v", 1, api.calls)
+	}
+}
+
+func TestCachedClientStore(t *testing.T) {
+	store := &memCacheStore{entries: make(map[string]cacheEntry)}
+	api := &countingAPI{}
+	opts := CacheOptions{LaunchpadTTL: time.Hour, LaunchesTTL: time.Hour, Store: store}
+	c, _ := newTestCachedClient(api, opts)
+	ts := time.Date(2050, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	available, err := c.IsLaunchpadAvailable(context.Background(), "lp1", ts)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !available {
+		t.Errorf("expected the launchpad to be available")
+		return
+	}
+
+	// a new process finds the responses in the store
+	restarted, _ := newTestCachedClient(api, opts)
+	if _, err := restarted.IsLaunchpadAvailable(context.Background(), "lp1", ts); err != nil {
+		t.Error(err)
+		return
+	}
+	if api.calls != 2 {
+		t.Errorf("expected %v but got %v", 2, api.calls)
+	}
+}
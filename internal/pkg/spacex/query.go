@@ -0,0 +1,99 @@
+package spacex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// QueryPageSize is the number of documents asked per page of a query.
+const QueryPageSize = 100
+
+var ErrBadPagination = errors.New("spacex returned an invalid page")
+
+// SearchQuery is the body of the query endpoints of the SpaceX API, see
+// https://github.com/r-spacex/SpaceX-API/blob/master/docs/queries.md
+// The page and limit options are set by QueryPaginated.
+type SearchQuery struct {
+	Query   map[string]interface{} `json:"query"`
+	Options map[string]interface{} `json:"options"`
+}
+
+type queryPage struct {
+	Docs        []json.RawMessage `json:"docs"`
+	TotalDocs   int               `json:"totalDocs"`
+	Page        int               `json:"page"`
+	HasNextPage bool              `json:"hasNextPage"`
+	NextPage    *int              `json:"nextPage"`
+}
+
+// QueryPaginated posts q to the query endpoint of resource, e.g. "launches", and calls fn
+// with every document of every page in order. It stops at the first error of fn.
+func (o *SpaceXClient) QueryPaginated(ctx context.Context, resource string, q SearchQuery, fn func(doc json.RawMessage) error) error {
+	u := fmt.Sprintf("%s/%s/query", o.baseUrl, resource)
+	read := 0
+	for page := 1; ; {
+		p, err := o.queryPage(ctx, u, q, page)
+		if err != nil {
+			return err
+		}
+		for _, doc := range p.Docs {
+			if err := fn(doc); err != nil {
+				return err
+			}
+		}
+		read += len(p.Docs)
+		if !p.HasNextPage || read >= p.TotalDocs {
+			return nil
+		}
+		// a page that does not move forward would loop forever
+		if p.NextPage == nil || *p.NextPage <= page || len(p.Docs) == 0 {
+			return ErrBadPagination
+		}
+		page = *p.NextPage
+	}
+}
+
+func (o *SpaceXClient) queryPage(ctx context.Context, u string, q SearchQuery, page int) (queryPage, error) {
+	var ans queryPage
+	// the options of the caller are left untouched
+	options := make(map[string]interface{}, len(q.Options)+2)
+	for k, v := range q.Options {
+		options[k] = v
+	}
+	options["page"] = page
+	options["limit"] = QueryPageSize
+	jsonBytes, err := json.Marshal(SearchQuery{Query: q.Query, Options: options})
+	if err != nil {
+		return ans, err
+	}
+	resp, err := o.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(jsonBytes))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return ans, err
+	}
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return ans, ErrBadStatusCode
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ans, err
+	}
+	return ans, json.Unmarshal(body, &ans)
+}
@@ -0,0 +1,100 @@
+package spacex
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newLaunchesServer serves the active launchpad lp1 and pages its n upcoming launches
+// the way the launches/query endpoint does.
+func newLaunchesServer(n int) (*httptest.Server, *[]int) {
+	var pages []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/launchpads/lp1" {
+			_, _ = w.Write([]byte(`{"id":"lp1","status":"active"}`))
+			return
+		}
+		if r.URL.Path != "/launches/query" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var q SearchQuery
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		page := int(q.Options["page"].(float64))
+		limit := int(q.Options["limit"].(float64))
+		pages = append(pages, page)
+
+		resp := queryPage{TotalDocs: n, Page: page}
+		for i := (page - 1) * limit; i < page*limit && i < n; i++ {
+			doc, _ := json.Marshal(Launch{LaunchPadID: "lp1", Date: 2524608000 + int64(i)*86400, DatePrecision: "day"})
+			resp.Docs = append(resp.Docs, doc)
+		}
+		if page*limit < n {
+			next := page + 1
+			resp.HasNextPage = true
+			resp.NextPage = &next
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	return srv, &pages
+}
+
+func TestQueryUpcomingLaunchesPagination(t *testing.T) {
+	srv, pages := newLaunchesServer(2*QueryPageSize + 1)
+	defer srv.Close()
+	c := NewSpaceXClient(srv.URL)
+
+	launches, err := c.QueryUpcomingLaunchesLaunchPad(context.Background(), "lp1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(launches) != 2*QueryPageSize+1 {
+		t.Errorf("expected %v but got %v", 2*QueryPageSize+1, len(launches))
+		return
+	}
+	if len(*pages) != 3 || (*pages)[2] != 3 {
+		t.Errorf("expected %v but got %v", []int{1, 2, 3}, *pages)
+		return
+	}
+
+	// the last launch is on the third page
+	ts := time.Unix(launches[len(launches)-1].Date, 0).UTC()
+	available, err := c.IsLaunchpadAvailable(context.Background(), "lp1", ts)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if available {
+		t.Errorf("expected the launchpad to be taken on %v", ts)
+	}
+}
+
+func TestQueryPaginatedStopsOnError(t *testing.T) {
+	srv, pages := newLaunchesServer(2 * QueryPageSize)
+	defer srv.Close()
+	c := NewSpaceXClient(srv.URL)
+
+	read := 0
+	err := c.QueryPaginated(context.Background(), "launches", SearchQuery{}, func(doc json.RawMessage) error {
+		read++
+		if read == QueryPageSize {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != ErrNotFound {
+		t.Errorf("expected %v but got %v", ErrNotFound, err)
+		return
+	}
+	if len(*pages) != 1 {
+		t.Errorf("expected %v but got %v", 1, len(*pages))
+	}
+}
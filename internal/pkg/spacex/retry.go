@@ -0,0 +1,171 @@
+package spacex
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("spacex circuit breaker is open")
+
+// RetryPolicy retries the requests failing with a network error, 429 or 5xx up to MaxAttempts.
+// Delays double from BaseDelay up to MaxDelay with jitter. A Retry-After header replaces the
+// delay, unless it exceeds MaxDelay and then the failed response is returned right away.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// backoff returns the delay before the attempt following the given one,
+// half of it fixed and the other half random.
+func (o RetryPolicy) backoff(attempt int) time.Duration {
+	d := o.BaseDelay
+	for i := 1; i < attempt && d < o.MaxDelay; i++ {
+		d *= 2
+	}
+	if o.MaxDelay > 0 && d > o.MaxDelay {
+		d = o.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// BreakerPolicy opens the circuit after FailureThreshold consecutive failed calls, retries included.
+// Calls then fail fast with ErrCircuitOpen for OpenFor, after which a single call is let through
+// to close the circuit again, or open it for another OpenFor when it fails.
+// The circuit never opens when FailureThreshold is 0.
+type BreakerPolicy struct {
+	FailureThreshold int
+	OpenFor          time.Duration
+}
+
+type breaker struct {
+	policy BreakerPolicy
+	now    func() time.Time
+
+	lock      sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (o *breaker) allow() error {
+	if o.policy.FailureThreshold <= 0 {
+		return nil
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.openUntil.IsZero() {
+		return nil
+	}
+	if o.now().Before(o.openUntil) || o.probing {
+		return ErrCircuitOpen
+	}
+	o.probing = true
+	return nil
+}
+
+func (o *breaker) record(success bool) {
+	if o.policy.FailureThreshold <= 0 {
+		return
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if success {
+		o.failures = 0
+		o.openUntil = time.Time{}
+		o.probing = false
+		return
+	}
+	o.failures++
+	if o.probing || o.failures >= o.policy.FailureThreshold {
+		o.openUntil = o.now().Add(o.policy.OpenFor)
+		o.probing = false
+	}
+}
+
+// cancel lets another call probe the circuit when the probing one was given up.
+func (o *breaker) cancel() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.probing = false
+}
+
+func isFailureStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// do sends the request built by newReq, once per attempt, through the circuit breaker.
+// A call fails for the breaker when its last attempt got a network error, 429 or 5xx,
+// a call given up by the caller does not count.
+func (o *SpaceXClient) do(ctx context.Context, newReq func() (*http.Request, error)) (*http.Response, error) {
+	if err := o.breaker.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := o.doWithRetries(ctx, newReq)
+	if ctx.Err() != nil {
+		o.breaker.cancel()
+	} else {
+		o.breaker.record(err == nil && !isFailureStatus(resp.StatusCode))
+	}
+	return resp, err
+}
+
+func (o *SpaceXClient) doWithRetries(ctx context.Context, newReq func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := o.httpclient.Do(req)
+		if attempt >= o.retry.MaxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+		wait := o.retry.backoff(attempt)
+		if err == nil {
+			if !isFailureStatus(resp.StatusCode) {
+				return resp, nil
+			}
+			if d, ok := retryAfter(resp.Header.Get("Retry-After"), o.breaker.now()); ok {
+				if d > o.retry.MaxDelay {
+					return resp, nil
+				}
+				wait = d
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
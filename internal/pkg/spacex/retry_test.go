@@ -0,0 +1,103 @@
+package spacex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newStatusServer answers the launchpad requests with the given statuses in turn, then with the last one.
+func newStatusServer(statuses []int, header http.Header) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n > len(statuses) {
+			n = len(statuses)
+		}
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(statuses[n-1])
+		if statuses[n-1] == http.StatusOK {
+			_, _ = w.Write([]byte(`{"id":"lp1","status":"active"}`))
+		}
+	}))
+	return srv, &calls
+}
+
+func TestSpaceXClientRetries(t *testing.T) {
+	srv, calls := newStatusServer([]int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, nil)
+	defer srv.Close()
+	c := NewSpaceXClient(srv.URL, WithRetries(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}))
+
+	lp, err := c.GetLaunchPadById(context.Background(), "lp1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if lp.Id != "lp1" {
+		t.Errorf("expected %v but got %v", "lp1", lp.Id)
+		return
+	}
+	if *calls != 3 {
+		t.Errorf("expected %v but got %v", 3, *calls)
+	}
+}
+
+func TestSpaceXClientRetryAfter(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"60"}}
+	srv, calls := newStatusServer([]int{http.StatusTooManyRequests, http.StatusOK}, header)
+	defer srv.Close()
+	c := NewSpaceXClient(srv.URL, WithRetries(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}))
+
+	// waiting a minute exceeds the max delay, the 429 is returned right away
+	if _, err := c.GetLaunchPadById(context.Background(), "lp1"); err != ErrBadStatusCode {
+		t.Errorf("expected %v but got %v", ErrBadStatusCode, err)
+		return
+	}
+	if *calls != 1 {
+		t.Errorf("expected %v but got %v", 1, *calls)
+	}
+
+	d, ok := retryAfter("Wed, 01 Jan 2049 00:00:30 GMT", time.Date(2049, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !ok || d != 30*time.Second {
+		t.Errorf("expected %v but got %v", 30*time.Second, d)
+	}
+}
+
+func TestSpaceXClientCircuitBreaker(t *testing.T) {
+	srv, calls := newStatusServer([]int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK}, nil)
+	defer srv.Close()
+	c := NewSpaceXClient(srv.URL, WithCircuitBreaker(BreakerPolicy{FailureThreshold: 2, OpenFor: time.Minute}))
+	now := time.Date(2049, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetLaunchPadById(ctx, "lp1"); err != ErrBadStatusCode {
+			t.Errorf("expected %v but got %v", ErrBadStatusCode, err)
+			return
+		}
+	}
+	if _, err := c.GetLaunchPadById(ctx, "lp1"); err != ErrCircuitOpen {
+		t.Errorf("expected %v but got %v", ErrCircuitOpen, err)
+		return
+	}
+	if *calls != 2 {
+		t.Errorf("expected %v but got %v", 2, *calls)
+		return
+	}
+
+	// once open for long enough, a call probes the API and closes the circuit
+	now = now.Add(time.Minute)
+	if _, err := c.GetLaunchPadById(ctx, "lp1"); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := c.GetLaunchPadById(ctx, "lp1"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,141 @@
+package spacex
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+var ErrScheduleStale = errors.New("spacex schedule is stale")
+
+// ScheduleSource is the part of the SpaceX API the schedule is synced from.
+type ScheduleSource interface {
+	GetLaunchPads(ctx context.Context) ([]LaunchPad, error)
+	QueryUpcomingLaunches(ctx context.Context) ([]Launch, error)
+}
+
+// ScheduleStore keeps the launchpads and upcoming launches of the last sync.
+// GetSpaceXSyncedAt and GetSpaceXLaunchpad return entity.ErrNotFound when missing.
+type ScheduleStore interface {
+	ReplaceSpaceXSchedule(ctx context.Context, launchpads []entity.SpaceXLaunchpad, launches []entity.SpaceXLaunch, syncedAt time.Time) error
+	GetSpaceXSyncedAt(ctx context.Context) (time.Time, error)
+	GetSpaceXLaunchpad(ctx context.Context, id string) (entity.SpaceXLaunchpad, error)
+	GetSpaceXLaunches(ctx context.Context, launchpadID string) ([]entity.SpaceXLaunch, error)
+}
+
+// ScheduleSync is the outcome of a sync.
+type ScheduleSync struct {
+	Launchpads int
+	Launches   int
+	SyncedAt   time.Time
+}
+
+// SyncSchedule replaces the schedule of the store with the launchpads and upcoming launches of src.
+// The store is left untouched when src fails.
+func SyncSchedule(ctx context.Context, src ScheduleSource, store ScheduleStore, now time.Time) (ScheduleSync, error) {
+	ans := ScheduleSync{SyncedAt: now}
+	launchpads, err := src.GetLaunchPads(ctx)
+	if err != nil {
+		return ans, err
+	}
+	launches, err := src.QueryUpcomingLaunches(ctx)
+	if err != nil {
+		return ans, err
+	}
+
+	pads := make([]entity.SpaceXLaunchpad, 0, len(launchpads))
+	for _, l := range launchpads {
+		pads = append(pads, entity.SpaceXLaunchpad{ID: l.Id, Status: l.Status})
+	}
+	items := make([]entity.SpaceXLaunch, 0, len(launches))
+	for _, l := range launches {
+		items = append(items, entity.SpaceXLaunch{
+			ID:            l.Id,
+			LaunchpadID:   l.LaunchPadID,
+			Date:          time.Unix(l.Date, 0).UTC(),
+			DatePrecision: l.DatePrecision,
+		})
+	}
+	if err := store.ReplaceSpaceXSchedule(ctx, pads, items, now); err != nil {
+		return ans, err
+	}
+	ans.Launchpads, ans.Launches = len(pads), len(items)
+	return ans, nil
+}
+
+// ScheduleOptions configures ScheduleClient. The synced schedule is used for MaxStale after
+// the last sync, then Fallback is called instead, or ErrScheduleStale is returned without one.
+type ScheduleOptions struct {
+	MaxStale time.Duration
+	Fallback API
+}
+
+// ScheduleClient tells the availability of the launchpads from the schedule synced by SyncSchedule,
+// so that the bookings neither wait for the SpaceX API nor fail with it.
+type ScheduleClient struct {
+	store ScheduleStore
+	opts  ScheduleOptions
+	now   func() time.Time
+}
+
+func NewScheduleClient(store ScheduleStore, opts ScheduleOptions) *ScheduleClient {
+	return &ScheduleClient{
+		store: store,
+		opts:  opts,
+		now:   time.Now,
+	}
+}
+
+func (o *ScheduleClient) IsLaunchpadAvailable(ctx context.Context, launchpadID string, ts time.Time) (bool, error) {
+	if o.fresh(ctx) {
+		return isLaunchpadAvailable(ctx, scheduleAPI{store: o.store}, launchpadID, ts)
+	}
+	if o.opts.Fallback == nil {
+		return false, ErrScheduleStale
+	}
+	return isLaunchpadAvailable(ctx, o.opts.Fallback, launchpadID, ts)
+}
+
+// fresh tells whether the last sync is recent enough, a schedule that cannot be read is not.
+func (o *ScheduleClient) fresh(ctx context.Context) bool {
+	syncedAt, err := o.store.GetSpaceXSyncedAt(ctx)
+	if err != nil {
+		return false
+	}
+	return o.now().Sub(syncedAt) <= o.opts.MaxStale
+}
+
+// scheduleAPI answers like the SpaceX API from the synced schedule.
+type scheduleAPI struct {
+	store ScheduleStore
+}
+
+func (o scheduleAPI) GetLaunchPadById(ctx context.Context, launchpadID string) (LaunchPad, error) {
+	l, err := o.store.GetSpaceXLaunchpad(ctx, launchpadID)
+	if errors.Is(err, entity.ErrNotFound) {
+		return LaunchPad{}, ErrNotFound
+	}
+	if err != nil {
+		return LaunchPad{}, err
+	}
+	return LaunchPad{Id: l.ID, Status: l.Status}, nil
+}
+
+func (o scheduleAPI) QueryUpcomingLaunchesLaunchPad(ctx context.Context, launchpadID string) ([]Launch, error) {
+	launches, err := o.store.GetSpaceXLaunches(ctx, launchpadID)
+	if err != nil {
+		return nil, err
+	}
+	ans := make([]Launch, 0, len(launches))
+	for _, l := range launches {
+		ans = append(ans, Launch{
+			Id:            l.ID,
+			LaunchPadID:   l.LaunchpadID,
+			Date:          l.Date.Unix(),
+			DatePrecision: l.DatePrecision,
+		})
+	}
+	return ans, nil
+}
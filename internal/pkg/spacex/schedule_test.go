@@ -0,0 +1,140 @@
+package spacex
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"spacetrouble/internal/pkg/entity"
+	"spacetrouble/internal/pkg/spacex/spacextest"
+)
+
+type memScheduleStore struct {
+	lock       sync.Mutex
+	launchpads []entity.SpaceXLaunchpad
+	launches   []entity.SpaceXLaunch
+	syncedAt   time.Time
+}
+
+func (o *memScheduleStore) ReplaceSpaceXSchedule(ctx context.Context, launchpads []entity.SpaceXLaunchpad,
+	launches []entity.SpaceXLaunch, syncedAt time.Time) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.launchpads, o.launches, o.syncedAt = launchpads, launches, syncedAt
+	return nil
+}
+
+func (o *memScheduleStore) GetSpaceXSyncedAt(ctx context.Context) (time.Time, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.syncedAt.IsZero() {
+		return o.syncedAt, entity.ErrNotFound
+	}
+	return o.syncedAt, nil
+}
+
+func (o *memScheduleStore) GetSpaceXLaunchpad(ctx context.Context, id string) (entity.SpaceXLaunchpad, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for _, l := range o.launchpads {
+		if l.ID == id {
+			return l, nil
+		}
+	}
+	return entity.SpaceXLaunchpad{}, entity.ErrNotFound
+}
+
+func (o *memScheduleStore) GetSpaceXLaunches(ctx context.Context, launchpadID string) ([]entity.SpaceXLaunch, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	var ans []entity.SpaceXLaunch
+	for _, l := range o.launches {
+		if l.LaunchpadID == launchpadID {
+			ans = append(ans, l)
+		}
+	}
+	return ans, nil
+}
+
+func TestSyncSchedule(t *testing.T) {
+	srv := spacextest.NewServer(spacextest.DefaultFixtures())
+	defer srv.Close()
+	store := &memScheduleStore{}
+	now := time.Date(2049, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s, err := SyncSchedule(context.Background(), NewSpaceXClient(srv.URL), store, now)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if s.Launchpads != 6 || s.Launches != 4 {
+		t.Errorf("expected %v but got %+v", "6 launchpads and 4 launches", s)
+		return
+	}
+	if !store.syncedAt.Equal(now) {
+		t.Errorf("expected %v but got %v", now, store.syncedAt)
+		return
+	}
+
+	// the API is down, the previous schedule is kept
+	srv.Close()
+	if _, err := SyncSchedule(context.Background(), NewSpaceXClient(srv.URL), store, now.Add(time.Hour)); err == nil {
+		t.Errorf("expected an error")
+		return
+	}
+	if !store.syncedAt.Equal(now) || len(store.launches) != 4 {
+		t.Errorf("expected the schedule of %v but got %v", now, store.syncedAt)
+	}
+}
+
+func TestScheduleClient(t *testing.T) {
+	srv := spacextest.NewServer(spacextest.DefaultFixtures())
+	defer srv.Close()
+	store := &memScheduleStore{}
+	syncedAt := time.Date(2049, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := SyncSchedule(context.Background(), NewSpaceXClient(srv.URL), store, syncedAt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	fallback := &countingAPI{}
+	c := NewScheduleClient(store, ScheduleOptions{MaxStale: time.Hour, Fallback: fallback})
+	now := syncedAt.Add(30 * time.Minute)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	available, err := c.IsLaunchpadAvailable(ctx, "5e9e4501f509094ba4566f84", time.Date(2049, 10, 27, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if available {
+		t.Errorf("expected the launchpad to be taken by its launch")
+		return
+	}
+	if _, err := c.IsLaunchpadAvailable(ctx, "000000000000000000000000", syncedAt); err != ErrNotFound {
+		t.Errorf("expected %v but got %v", ErrNotFound, err)
+		return
+	}
+	if fallback.calls != 0 {
+		t.Errorf("expected %v but got %v", 0, fallback.calls)
+		return
+	}
+
+	// once stale the API is called instead
+	now = syncedAt.Add(2 * time.Hour)
+	if _, err := c.IsLaunchpadAvailable(ctx, "5e9e4501f509094ba4566f84", syncedAt); err != nil {
+		t.Error(err)
+		return
+	}
+	if fallback.calls != 2 {
+		t.Errorf("expected %v but got %v", 2, fallback.calls)
+		return
+	}
+
+	c.opts.Fallback = nil
+	if _, err := c.IsLaunchpadAvailable(ctx, "5e9e4501f509094ba4566f84", syncedAt); err != ErrScheduleStale {
+		t.Errorf("expected %v but got %v", ErrScheduleStale, err)
+	}
+}
@@ -1,7 +1,6 @@
 package spacex
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -17,6 +16,7 @@ var (
 )
 
 type Launch struct {
+	Id          string `json:"id"`
 	LaunchPadID string `json:"launchpad"`
 	Date        int64  `json:"date_unix"`
 	// date_precision - Gives the date precision for partial dates.
@@ -61,27 +61,55 @@ func (o *LaunchPad) IsActive() bool {
 type SpaceXClient struct {
 	httpclient *http.Client
 	baseUrl    string
+	retry      RetryPolicy
+	breaker    *breaker
 }
 
-func NewSpaceXClient(baseUrl string) *SpaceXClient {
+// ClientOption customizes the SpaceX client.
+type ClientOption func(*SpaceXClient)
+
+func WithRetries(p RetryPolicy) ClientOption {
+	return func(o *SpaceXClient) {
+		o.retry = p
+	}
+}
+
+func WithCircuitBreaker(p BreakerPolicy) ClientOption {
+	return func(o *SpaceXClient) {
+		o.breaker.policy = p
+	}
+}
+
+// NewSpaceXClient makes a single attempt per request and never opens its circuit unless configured otherwise.
+func NewSpaceXClient(baseUrl string, opts ...ClientOption) *SpaceXClient {
 	ans := SpaceXClient{
 		httpclient: &http.Client{
 			Timeout: 15 * time.Second,
 		},
 		baseUrl: baseUrl,
+		retry:   RetryPolicy{MaxAttempts: 1},
+		breaker: &breaker{now: time.Now},
+	}
+	for _, opt := range opts {
+		opt(&ans)
 	}
 	return &ans
 }
 
 func (o *SpaceXClient) IsLaunchpadAvailable(ctx context.Context, launchpadID string, ts time.Time) (bool, error) {
-	launchpad, err := o.GetLaunchPadById(ctx, launchpadID)
+	return isLaunchpadAvailable(ctx, o, launchpadID, ts)
+}
+
+// isLaunchpadAvailable tells whether the launchpad is active and has no upcoming launch on the day.
+func isLaunchpadAvailable(ctx context.Context, api API, launchpadID string, ts time.Time) (bool, error) {
+	launchpad, err := api.GetLaunchPadById(ctx, launchpadID)
 	if err != nil {
 		return false, err
 	}
 	if !launchpad.IsActive() {
 		return false, nil
 	}
-	upcoming, err := o.QueryUpcomingLaunchesLaunchPad(ctx, launchpadID)
+	upcoming, err := api.QueryUpcomingLaunchesLaunchPad(ctx, launchpadID)
 	if err != nil {
 		return false, err
 	}
@@ -101,12 +129,14 @@ func (o *SpaceXClient) IsLaunchpadAvailable(ctx context.Context, launchpadID str
 func (o *SpaceXClient) GetLaunchPadById(ctx context.Context, launchpadID string) (LaunchPad, error) {
 	var ans LaunchPad
 	u := fmt.Sprintf("%s/%s/%s", o.baseUrl, "launchpads", launchpadID)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-	if err != nil {
-		return ans, err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	resp, err := o.httpclient.Do(req)
+	resp, err := o.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return ans, err
 	}
@@ -130,26 +160,18 @@ func (o *SpaceXClient) GetLaunchPadById(ctx context.Context, launchpadID string)
 	return ans, json.Unmarshal(body, &ans)
 }
 
-type launchesResp struct {
-	Launches []Launch `json:"docs"`
-}
-
-// TODO follow pagination
-// for the context of the task I just put a huge limit
-// Since we are querying the upcoming launches of a specific launchpad we are probably covered for now
-func (o *SpaceXClient) QueryUpcomingLaunchesLaunchPad(ctx context.Context, launchpadID string) ([]Launch, error) {
-	u := fmt.Sprintf("%s/%s", o.baseUrl, "launches/query")
-	q := o.buildUpcomingQuery(launchpadID)
-	jsonBytes, err := json.Marshal(q)
-	if err != nil {
-		return nil, err
-	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewBuffer(jsonBytes))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	resp, err := o.httpclient.Do(req)
+// GetLaunchPads returns all the launchpads, whatever their status.
+func (o *SpaceXClient) GetLaunchPads(ctx context.Context) ([]LaunchPad, error) {
+	var ans []LaunchPad
+	u := fmt.Sprintf("%s/%s", o.baseUrl, "launchpads")
+	resp, err := o.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -158,8 +180,7 @@ func (o *SpaceXClient) QueryUpcomingLaunchesLaunchPad(ctx context.Context, launc
 		resp.Body.Close()
 	}()
 
-	if resp.StatusCode != 200 {
-		fmt.Println(resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
 		return nil, ErrBadStatusCode
 	}
 
@@ -167,27 +188,46 @@ func (o *SpaceXClient) QueryUpcomingLaunchesLaunchPad(ctx context.Context, launc
 	if err != nil {
 		return nil, err
 	}
-	var launchesRes launchesResp
-	if err := json.Unmarshal(body, &launchesRes); err != nil {
-		return nil, err
-	}
-	return launchesRes.Launches, nil
+	return ans, json.Unmarshal(body, &ans)
 }
 
-type searchQ struct {
-	Query   map[string]interface{} `json:"query"`
-	Options map[string]interface{} `json:"options"`
+// QueryUpcomingLaunchesLaunchPad returns the upcoming launches of the launchpad sorted by date.
+func (o *SpaceXClient) QueryUpcomingLaunchesLaunchPad(ctx context.Context, launchpadID string) ([]Launch, error) {
+	return o.queryLaunches(ctx, o.buildUpcomingQuery(launchpadID))
+}
+
+// QueryUpcomingLaunches returns the upcoming launches of all the launchpads sorted by date.
+func (o *SpaceXClient) QueryUpcomingLaunches(ctx context.Context) ([]Launch, error) {
+	return o.queryLaunches(ctx, o.buildUpcomingQuery(""))
+}
+
+func (o *SpaceXClient) queryLaunches(ctx context.Context, q SearchQuery) ([]Launch, error) {
+	var ans []Launch
+	err := o.QueryPaginated(ctx, "launches", q, func(doc json.RawMessage) error {
+		var l Launch
+		if err := json.Unmarshal(doc, &l); err != nil {
+			return err
+		}
+		ans = append(ans, l)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ans, nil
 }
 
-func (o *SpaceXClient) buildUpcomingQuery(launchpadID string) searchQ {
-	searchQ := searchQ{
+// buildUpcomingQuery queries the upcoming launches of the launchpad, of all of them when launchpadID is empty.
+func (o *SpaceXClient) buildUpcomingQuery(launchpadID string) SearchQuery {
+	searchQ := SearchQuery{
 		Query:   make(map[string]interface{}),
 		Options: make(map[string]interface{}),
 	}
 	searchQ.Options["sort"] = map[string]string{"date_unix": "asc"}
 	searchQ.Options["select"] = []string{"launchpad", "date_unix", "date_precision"}
-	searchQ.Options["limit"] = 10000
 	searchQ.Query["upcoming"] = true
-	searchQ.Query["launchpad"] = launchpadID
+	if launchpadID != "" {
+		searchQ.Query["launchpad"] = launchpadID
+	}
 	return searchQ
 }
@@ -0,0 +1,43 @@
+package spacex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"spacetrouble/internal/pkg/spacex/spacextest"
+)
+
+func TestIsLaunchpadAvailable(t *testing.T) {
+	srv := spacextest.NewServer(spacextest.DefaultFixtures())
+	defer srv.Close()
+	c := NewSpaceXClient(srv.URL)
+
+	tests := []struct {
+		name        string
+		launchpadID string
+		date        time.Time
+		available   bool
+		err         error
+	}{
+		{"launch day", "5e9e4501f509094ba4566f84", time.Date(2049, 10, 27, 0, 0, 0, 0, time.UTC), false, nil},
+		{"launch month", "5e9e4501f509094ba4566f84", time.Date(2049, 11, 15, 0, 0, 0, 0, time.UTC), false, nil},
+		{"after the launches", "5e9e4501f509094ba4566f84", time.Date(2049, 12, 1, 0, 0, 0, 0, time.UTC), true, nil},
+		{"launch quarter", "5e9e4502f509094188566f88", time.Date(2049, 12, 31, 0, 0, 0, 0, time.UTC), false, nil},
+		{"after the quarter", "5e9e4502f509094188566f88", time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC), true, nil},
+		{"retired", "5e9e4501f5090910d4566f83", time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC), false, nil},
+		{"missing", "000000000000000000000000", time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC), false, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			available, err := c.IsLaunchpadAvailable(context.Background(), tt.launchpadID, tt.date)
+			if err != tt.err {
+				t.Errorf("expected %v but got %v", tt.err, err)
+				return
+			}
+			if available != tt.available {
+				t.Errorf("expected %v but got %v", tt.available, available)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+// Package spacextest serves a stand-in of the SpaceX v4 API from fixture files,
+// for the tests through httptest and for local runs through cmd/fake-spacex.
+package spacextest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//go:embed fixtures/*.json
+var defaultFixtures embed.FS
+
+// Fixtures are the documents of every resource, e.g. "launchpads", each one with an "id".
+type Fixtures map[string][]map[string]interface{}
+
+// DefaultFixtures returns the fixtures shipped with the package, a few launchpads
+// and their launches, the upcoming ones being in 2049.
+func DefaultFixtures() Fixtures {
+	fsys, err := fs.Sub(defaultFixtures, "fixtures")
+	if err != nil {
+		panic(err)
+	}
+	f, err := loadFixtures(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// LoadFixtures reads every <resource>.json file of dir, each one an array of documents.
+func LoadFixtures(dir string) (Fixtures, error) {
+	return loadFixtures(os.DirFS(dir))
+}
+
+func loadFixtures(fsys fs.FS) (Fixtures, error) {
+	names, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	ans := make(Fixtures, len(names))
+	for _, name := range names {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var docs []map[string]interface{}
+		if err := json.Unmarshal(b, &docs); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		ans[strings.TrimSuffix(name, path.Ext(name))] = docs
+	}
+	return ans, nil
+}
+
+// NewServer starts a server answering like https://api.spacexdata.com/v4, its URL is the base url of the client.
+func NewServer(f Fixtures) *httptest.Server {
+	return httptest.NewServer(NewHandler(f))
+}
+
+// NewHandler serves for every resource of the fixtures
+//
+//	GET /{resource}          all the documents
+//	GET /{resource}/{id}     a document, 404 when missing
+//	POST /{resource}/query   the documents matching the query, paginated
+//
+// Queries only match fields by equality, and options support sort, select, page, limit and pagination.
+func NewHandler(f Fixtures) http.Handler {
+	return &handler{fixtures: f}
+}
+
+type handler struct {
+	fixtures Fixtures
+}
+
+func (o *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	docs, ok := o.fixtures[parts[0]]
+	if !ok || len(parts) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, docs)
+	case len(parts) == 2 && parts[1] == "query" && r.Method == http.MethodPost:
+		var q query
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, q.run(docs))
+	case len(parts) == 2 && parts[1] != "query" && r.Method == http.MethodGet:
+		for _, doc := range docs {
+			if doc["id"] == parts[1] {
+				writeJSON(w, doc)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+type query struct {
+	Query   map[string]interface{} `json:"query"`
+	Options struct {
+		Sort       map[string]interface{} `json:"sort"`
+		Select     interface{}            `json:"select"`
+		Page       int                    `json:"page"`
+		Limit      int                    `json:"limit"`
+		Pagination *bool                  `json:"pagination"`
+	} `json:"options"`
+}
+
+// page is the paginated response of the query endpoints.
+type page struct {
+	Docs          []map[string]interface{} `json:"docs"`
+	TotalDocs     int                      `json:"totalDocs"`
+	Offset        int                      `json:"offset"`
+	Limit         int                      `json:"limit"`
+	TotalPages    int                      `json:"totalPages"`
+	Page          int                      `json:"page"`
+	PagingCounter int                      `json:"pagingCounter"`
+	HasPrevPage   bool                     `json:"hasPrevPage"`
+	HasNextPage   bool                     `json:"hasNextPage"`
+	PrevPage      *int                     `json:"prevPage"`
+	NextPage      *int                     `json:"nextPage"`
+}
+
+func (o query) run(docs []map[string]interface{}) page {
+	var matched []map[string]interface{}
+	for _, doc := range docs {
+		if o.matches(doc) {
+			matched = append(matched, doc)
+		}
+	}
+	o.sort(matched)
+
+	ans := page{TotalDocs: len(matched), Page: 1, Limit: len(matched)}
+	if o.Options.Pagination == nil || *o.Options.Pagination {
+		// the defaults of the SpaceX API
+		ans.Page, ans.Limit = o.Options.Page, o.Options.Limit
+		if ans.Page < 1 {
+			ans.Page = 1
+		}
+		if ans.Limit < 1 {
+			ans.Limit = 10
+		}
+	}
+	if ans.Limit > 0 {
+		ans.TotalPages = (ans.TotalDocs + ans.Limit - 1) / ans.Limit
+	}
+	ans.Offset = (ans.Page - 1) * ans.Limit
+	ans.PagingCounter = ans.Offset + 1
+	if ans.Page > 1 {
+		prev := ans.Page - 1
+		ans.HasPrevPage, ans.PrevPage = true, &prev
+	}
+	if ans.Page < ans.TotalPages {
+		next := ans.Page + 1
+		ans.HasNextPage, ans.NextPage = true, &next
+	}
+
+	ans.Docs = make([]map[string]interface{}, 0, ans.Limit)
+	for i := ans.Offset; i < ans.Offset+ans.Limit && i < len(matched); i++ {
+		ans.Docs = append(ans.Docs, o.selectFields(matched[i]))
+	}
+	return ans
+}
+
+func (o query) matches(doc map[string]interface{}) bool {
+	for k, v := range o.Query {
+		if !reflect.DeepEqual(doc[k], v) {
+			return false
+		}
+	}
+	return true
+}
+
+// sort orders docs by the sort fields in alphabetical order, as their order is lost in the map.
+func (o query) sort(docs []map[string]interface{}) {
+	fields := make([]string, 0, len(o.Options.Sort))
+	for k := range o.Options.Sort {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, f := range fields {
+			c := compare(docs[i][f], docs[j][f])
+			if c == 0 {
+				continue
+			}
+			switch o.Options.Sort[f] {
+			case "desc", "descending", -1.0:
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func compare(a, b interface{}) int {
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			switch {
+			case a < b:
+				return -1
+			case a > b:
+				return 1
+			}
+			return 0
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// selectFields keeps the selected fields and the id, select being a list or a space separated string.
+func (o query) selectFields(doc map[string]interface{}) map[string]interface{} {
+	var fields []string
+	switch v := o.Options.Select.(type) {
+	case string:
+		fields = strings.Fields(v)
+	case []interface{}:
+		for _, f := range v {
+			fields = append(fields, fmt.Sprint(f))
+		}
+	}
+	if len(fields) == 0 {
+		return doc
+	}
+	ans := map[string]interface{}{"id": doc["id"]}
+	for _, f := range fields {
+		if v, ok := doc[f]; ok {
+			ans[f] = v
+		}
+	}
+	return ans
+}
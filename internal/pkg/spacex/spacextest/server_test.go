@@ -0,0 +1,58 @@
+package spacextest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	srv := NewServer(DefaultFixtures())
+	defer srv.Close()
+
+	body := []byte(`{
+		"query": {"upcoming": true, "launchpad": "5e9e4501f509094ba4566f84"},
+		"options": {"sort": {"date_unix": "desc"}, "select": ["date_unix"], "limit": 1, "page": 2}
+	}`)
+	resp, err := http.Post(srv.URL+"/launches/query", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var p page
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		t.Error(err)
+		return
+	}
+	if p.TotalDocs != 2 || p.HasNextPage || !p.HasPrevPage {
+		t.Errorf("expected %v but got %+v", "the last of 2 pages", p)
+		return
+	}
+	if len(p.Docs) != 1 || p.Docs[0]["date_unix"] != 2518905600.0 {
+		t.Errorf("expected %v but got %v", 2518905600, p.Docs)
+		return
+	}
+	if _, ok := p.Docs[0]["launchpad"]; ok {
+		t.Errorf("expected %v to be left out", "launchpad")
+	}
+}
+
+func TestGetByID(t *testing.T) {
+	srv := NewServer(DefaultFixtures())
+	defer srv.Close()
+
+	for id, status := range map[string]int{"5e9e4501f509094ba4566f84": http.StatusOK, "missing": http.StatusNotFound} {
+		resp, err := http.Get(srv.URL + "/launchpads/" + id)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("expected %v but got %v", status, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Package wiring builds the services shared by the binaries from the configuration.
+package wiring
+
+import (
+	"spacetrouble/internal/pkg/booking"
+	"spacetrouble/internal/pkg/config"
+	"spacetrouble/internal/pkg/data/postgres"
+	"spacetrouble/internal/pkg/payment"
+	"spacetrouble/internal/pkg/pricing"
+	"spacetrouble/internal/pkg/spacex"
+)
+
+// NewBookingService builds the booking service with the capacities, fares, payments
+// and SpaceX client of the configuration, opts are applied last.
+func NewBookingService(cfg *config.Config, store *postgres.Store, opts ...booking.Option) booking.BookingService {
+	capacity := booking.CapacityPolicy{
+		Default:       cfg.FlightCapacity,
+		ByLaunchpad:   cfg.FlightCapacityByLaunchpad,
+		ByDestination: cfg.FlightCapacityByDestination,
+	}
+	fares := pricing.Policy{
+		BaseFare:      cfg.BaseFare,
+		ByDestination: cfg.BaseFareByDestination,
+	}
+	opts = append([]booking.Option{
+		booking.WithCapacityPolicy(capacity),
+		booking.WithPricing(fares),
+		booking.WithPaymentWindow(cfg.PaymentWindow),
+		booking.WithCursorSecret([]byte(cfg.CursorSecret)),
+	}, opts...)
+	// the fake provider is the only payment provider so far
+	return booking.NewBookingService(store, NewSpaceXClient(cfg, store), payment.NewFakeProvider(), opts...)
+}
+
+// NewSpaceXClient retries the failed calls to the SpaceX API and caches its responses,
+// in postgres too when configured. The API is only called once the synced schedule is stale
+// when the schedule is enabled.
+func NewSpaceXClient(cfg *config.Config, store *postgres.Store) booking.SpaceX {
+	opts := spacex.CacheOptions{
+		Size:         cfg.SpaceXCacheSize,
+		LaunchpadTTL: cfg.SpaceXLaunchpadTTL,
+		LaunchesTTL:  cfg.SpaceXLaunchesTTL,
+		MaxStale:     cfg.SpaceXCacheMaxStale,
+	}
+	if cfg.SpaceXCachePersist {
+		opts.Store = store
+	}
+	client := spacex.NewSpaceXClient(cfg.SpaceXUrl,
+		spacex.WithRetries(spacex.RetryPolicy{
+			MaxAttempts: cfg.SpaceXMaxAttempts,
+			BaseDelay:   cfg.SpaceXRetryBaseDelay,
+			MaxDelay:    cfg.SpaceXRetryMaxDelay,
+		}),
+		spacex.WithCircuitBreaker(spacex.BreakerPolicy{
+			FailureThreshold: cfg.SpaceXBreakerThreshold,
+			OpenFor:          cfg.SpaceXBreakerOpenFor,
+		}),
+	)
+	cached := spacex.NewCachedClient(client, opts)
+	if !cfg.SpaceXSchedule {
+		return cached
+	}
+	return spacex.NewScheduleClient(store, spacex.ScheduleOptions{
+		MaxStale: cfg.SpaceXScheduleMaxStale,
+		Fallback: cached,
+	})
+}
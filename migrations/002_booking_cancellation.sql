@@ -0,0 +1,66 @@
+ALTER TABLE bookings ADD COLUMN cancelled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE bookings ADD COLUMN cancel_reason VARCHAR(255);
+
+ALTER TABLE flights ADD COLUMN status VARCHAR(10) NOT NULL DEFAULT 'scheduled';
+
+-- a cancelled flight no longer holds its launchpad for the date
+ALTER TABLE flights DROP CONSTRAINT flights_launchpad_id_launch_date_key;
+CREATE UNIQUE INDEX idx_flights_scheduled_launchpad_date ON flights (launchpad_id, launch_date) WHERE status = 'scheduled';
+
+ALTER TABLE flights DROP CONSTRAINT check_unique_launchpad_dest_in_week;
+
+CREATE OR REPLACE FUNCTION launch_in_same_week_except(flight UUID, pad CHAR(24), dest UUID, d DATE)
+RETURNS BOOLEAN
+language plpgsql
+AS
+$$
+BEGIN
+	PERFORM A.id FROM flights A WHERE A.id <> flight AND A.status = 'scheduled' AND A.launchpad_id = pad AND A.destination_id = dest AND date_part('week', A.launch_date) = date_part('week', d) LIMIT 1;
+	IF FOUND
+		THEN RETURN false;
+		ELSE RETURN true;
+	END IF;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION launch_in_same_week(pad CHAR(24), dest UUID, d DATE)
+RETURNS BOOLEAN
+language plpgsql
+AS
+$$
+BEGIN
+	RETURN launch_in_same_week_except(uuid_nil(), pad, dest, d);
+END;
+$$;
+
+ALTER TABLE flights ADD CONSTRAINT check_unique_launchpad_dest_in_week
+    CHECK(status <> 'scheduled' OR launch_in_same_week_except(id, launchpad_id, destination_id, launch_date));
+
+---- create above / drop below ----
+
+ALTER TABLE flights DROP CONSTRAINT check_unique_launchpad_dest_in_week;
+
+CREATE OR REPLACE FUNCTION launch_in_same_week(pad CHAR(24), dest UUID, d DATE)
+RETURNS BOOLEAN
+language plpgsql
+AS
+$$
+BEGIN
+	PERFORM A.id FROM flights A WHERE A.launchpad_id = pad AND A.destination_id = dest AND date_part('week', A.launch_date) = date_part('week', d) LIMIT 1;
+	IF FOUND
+		THEN RETURN false;
+		ELSE RETURN true;
+	END IF;
+END;
+$$;
+
+DROP FUNCTION launch_in_same_week_except;
+
+DROP INDEX idx_flights_scheduled_launchpad_date;
+DELETE FROM flights WHERE status <> 'scheduled';
+ALTER TABLE flights ADD CONSTRAINT flights_launchpad_id_launch_date_key UNIQUE(launchpad_id, launch_date);
+ALTER TABLE flights ADD CONSTRAINT check_unique_launchpad_dest_in_week CHECK(launch_in_same_week(launchpad_id, destination_id, launch_date)) NOT VALID;
+ALTER TABLE flights DROP COLUMN status;
+
+ALTER TABLE bookings DROP COLUMN cancel_reason;
+ALTER TABLE bookings DROP COLUMN cancelled_at;
@@ -0,0 +1,7 @@
+ALTER TABLE flights ADD COLUMN capacity INT NOT NULL DEFAULT 10;
+ALTER TABLE flights ADD CONSTRAINT check_capacity_positive CHECK(capacity > 0);
+
+---- create above / drop below ----
+
+ALTER TABLE flights DROP CONSTRAINT check_capacity_positive;
+ALTER TABLE flights DROP COLUMN capacity;
@@ -0,0 +1,25 @@
+CREATE TABLE waitlist(
+    id UUID PRIMARY KEY,
+    first_name VARCHAR(50) NOT NULL,
+    last_name VARCHAR(50) NOT NULL,
+    gender CHAR(1) NOT NULL,
+    birthday DATE NOT NULL,
+    launchpad_id CHAR(24) NOT NULL,
+    destination_id UUID NOT NULL,
+    launch_date DATE NOT NULL,
+    status VARCHAR(10) NOT NULL,
+    booking_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    promoted_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT fk_destination FOREIGN KEY(destination_id) REFERENCES destinations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_booking FOREIGN KEY(booking_id) REFERENCES bookings(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_waitlist_waiting ON waitlist (launchpad_id, launch_date, created_at) WHERE status = 'waiting';
+-- a passenger waits once for a launchpad and date
+CREATE UNIQUE INDEX idx_waitlist_passenger_waiting
+    ON waitlist (lower(first_name), lower(last_name), gender, birthday, launchpad_id, launch_date) WHERE status = 'waiting';
+
+---- create above / drop below ----
+
+DROP TABLE waitlist;
@@ -0,0 +1,11 @@
+CREATE TABLE idempotency_keys(
+    key VARCHAR(255) PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    status_code INT,
+    response BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+---- create above / drop below ----
+
+DROP TABLE idempotency_keys;
@@ -0,0 +1,12 @@
+-- a passenger may book again a flight they cancelled
+ALTER TABLE bookings DROP CONSTRAINT bookings_user_id_flight_id_key;
+CREATE UNIQUE INDEX idx_bookings_user_flight ON bookings (user_id, flight_id) WHERE status <> 'cancelled';
+
+CREATE INDEX idx_users_identity ON users (lower(first_name), lower(last_name), birthday);
+
+---- create above / drop below ----
+
+DROP INDEX idx_users_identity;
+
+DROP INDEX idx_bookings_user_flight;
+ALTER TABLE bookings ADD CONSTRAINT bookings_user_id_flight_id_key UNIQUE(user_id, flight_id);
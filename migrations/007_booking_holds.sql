@@ -0,0 +1,17 @@
+-- held bookings reserve a seat until expires_at, then the sweeper expires them
+ALTER TABLE bookings ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX idx_bookings_held_expiry ON bookings (expires_at) WHERE status = 'held';
+
+-- an expired hold does not prevent the passenger from booking the flight again
+DROP INDEX idx_bookings_user_flight;
+CREATE UNIQUE INDEX idx_bookings_user_flight ON bookings (user_id, flight_id) WHERE status NOT IN ('cancelled', 'expired');
+
+---- create above / drop below ----
+
+UPDATE bookings SET status = 'cancelled', cancelled_at = now() WHERE status IN ('held', 'expired');
+
+DROP INDEX idx_bookings_user_flight;
+CREATE UNIQUE INDEX idx_bookings_user_flight ON bookings (user_id, flight_id) WHERE status <> 'cancelled';
+
+DROP INDEX idx_bookings_held_expiry;
+ALTER TABLE bookings DROP COLUMN expires_at;
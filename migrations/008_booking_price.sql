@@ -0,0 +1,6 @@
+-- the price quoted when booking, in cents
+ALTER TABLE bookings ADD COLUMN price BIGINT NOT NULL DEFAULT 0 CHECK (price >= 0);
+
+---- create above / drop below ----
+
+ALTER TABLE bookings DROP COLUMN price;
@@ -0,0 +1,23 @@
+-- room for the pending_payment status
+ALTER TABLE bookings ALTER COLUMN status TYPE VARCHAR(20);
+
+CREATE TABLE payments(
+    id UUID PRIMARY KEY,
+    booking_id UUID NOT NULL,
+    kind VARCHAR(10) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount >= 0),
+    currency CHAR(3) NOT NULL,
+    provider_ref VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CONSTRAINT fk_booking FOREIGN KEY(booking_id) REFERENCES bookings(id) ON DELETE CASCADE
+);
+
+-- a booking is captured once and refunded once
+CREATE UNIQUE INDEX idx_payments_booking_kind ON payments (booking_id, kind);
+
+---- create above / drop below ----
+
+DROP TABLE payments;
+
+UPDATE bookings SET status = 'cancelled', cancelled_at = now() WHERE status = 'pending_payment';
+ALTER TABLE bookings ALTER COLUMN status TYPE VARCHAR(10);
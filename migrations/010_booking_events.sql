@@ -0,0 +1,46 @@
+-- append-only history of the bookings, written along with every change
+CREATE TABLE booking_events(
+    id BIGSERIAL PRIMARY KEY,
+    booking_id UUID NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    flight_id UUID NOT NULL,
+    previous_flight_id UUID,
+    details TEXT,
+    actor VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- the history is append-only, a booking that has one cannot be deleted
+    CONSTRAINT fk_booking FOREIGN KEY(booking_id) REFERENCES bookings(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_booking_events_booking ON booking_events (booking_id, id);
+
+CREATE OR REPLACE FUNCTION booking_events_append_only()
+RETURNS TRIGGER
+language plpgsql
+AS
+$$
+BEGIN
+	RAISE EXCEPTION 'booking_events is append-only';
+END;
+$$;
+
+CREATE TRIGGER booking_events_no_update BEFORE UPDATE ON booking_events
+    FOR EACH ROW EXECUTE FUNCTION booking_events_append_only();
+CREATE TRIGGER booking_events_no_delete BEFORE DELETE ON booking_events
+    FOR EACH ROW EXECUTE FUNCTION booking_events_append_only();
+CREATE TRIGGER booking_events_no_truncate BEFORE TRUNCATE ON booking_events
+    FOR EACH STATEMENT EXECUTE FUNCTION booking_events_append_only();
+
+-- the bookings made so far start their history with their creation
+INSERT INTO booking_events(booking_id, kind, to_status, flight_id, actor, created_at)
+SELECT id, 'created', status, flight_id, 'migration', created_at FROM bookings ORDER BY created_at, id;
+
+---- create above / drop below ----
+
+DROP TRIGGER booking_events_no_truncate ON booking_events;
+DROP TRIGGER booking_events_no_delete ON booking_events;
+DROP TRIGGER booking_events_no_update ON booking_events;
+DROP TABLE booking_events;
+DROP FUNCTION booking_events_append_only;
@@ -0,0 +1,10 @@
+-- responses of the SpaceX API kept by the cache of the booking server
+CREATE TABLE spacex_cache(
+    key VARCHAR(255) PRIMARY KEY,
+    value JSONB NOT NULL,
+    stored_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+---- create above / drop below ----
+
+DROP TABLE spacex_cache;
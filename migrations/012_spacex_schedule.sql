@@ -0,0 +1,25 @@
+-- launchpads and upcoming launches of the SpaceX API, replaced by every sync
+CREATE TABLE spacex_launchpads(
+    id CHAR(24) PRIMARY KEY,
+    status VARCHAR(64) NOT NULL
+);
+
+CREATE TABLE spacex_launches(
+    id CHAR(24) PRIMARY KEY,
+    launchpad_id CHAR(24) NOT NULL,
+    launch_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    date_precision VARCHAR(16) NOT NULL
+);
+CREATE INDEX idx_spacex_launches_launchpad ON spacex_launches (launchpad_id, launch_date);
+
+-- a single row with the time of the last successful sync
+CREATE TABLE spacex_syncs(
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK(id),
+    synced_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+---- create above / drop below ----
+
+DROP TABLE spacex_syncs;
+DROP TABLE spacex_launches;
+DROP TABLE spacex_launchpads;
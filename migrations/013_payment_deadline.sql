@@ -0,0 +1,23 @@
+-- pending payment bookings reserve a seat until their payment deadline in expires_at,
+-- then the sweeper expires them like holds
+DROP INDEX idx_bookings_held_expiry;
+CREATE INDEX idx_bookings_expiry ON bookings (expires_at) WHERE status IN ('held', 'pending_payment');
+
+-- the bookings of the waitlist and of the import command do not wait for a payment
+WITH activated AS (
+    UPDATE bookings B SET status = 'active'
+    WHERE B.status = 'pending_payment' AND EXISTS(SELECT 1 FROM booking_events E
+        WHERE E.booking_id = B.id AND E.kind = 'created' AND E.actor IN ('waitlist', 'import'))
+    RETURNING B.id, B.flight_id
+)
+INSERT INTO booking_events(booking_id, kind, from_status, to_status, flight_id, actor, created_at)
+SELECT id, 'confirmed', 'pending_payment', 'active', flight_id, 'migration', now() FROM activated ORDER BY id;
+
+UPDATE bookings SET expires_at = now() + interval '30 minutes' WHERE status = 'pending_payment' AND expires_at IS NULL;
+
+---- create above / drop below ----
+
+UPDATE bookings SET expires_at = NULL WHERE status = 'pending_payment';
+
+DROP INDEX idx_bookings_expiry;
+CREATE INDEX idx_bookings_held_expiry ON bookings (expires_at) WHERE status = 'held';
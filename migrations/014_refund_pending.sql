@@ -0,0 +1,9 @@
+-- room for the refund_pending kind, a refund the payment provider failed and the server retries
+ALTER TABLE payments ALTER COLUMN kind TYPE VARCHAR(20);
+CREATE INDEX idx_payments_refund_pending ON payments (created_at) WHERE kind = 'refund_pending';
+
+---- create above / drop below ----
+
+DROP INDEX idx_payments_refund_pending;
+DELETE FROM payments WHERE kind = 'refund_pending';
+ALTER TABLE payments ALTER COLUMN kind TYPE VARCHAR(10);
@@ -0,0 +1,118 @@
+package apiutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type IdempotencyStore interface {
+	// ClaimIdempotencyKey reserves the key for the request. When the key is already
+	// taken it returns the existing record and claimed is false.
+	ClaimIdempotencyKey(ctx context.Context, key, requestHash string) (rec entity.IdempotencyRecord, claimed bool, err error)
+	CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, body []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+// Idempotent replays the stored response of POST requests repeated with the same Idempotency-Key.
+// Reusing a key for a different request is rejected with 422. Responses with
+// status 5xx are not stored so that the request can be retried.
+func Idempotent(next http.HandlerFunc, store IdempotencyStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+		if len(key) > 255 {
+			ae := NewBadRequest("Idempotency-Key must be less than 255 chars")
+			RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			ae := NewBadRequest("error reading body")
+			RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := requestHash(r, body)
+
+		rec, claimed, err := store.ClaimIdempotencyKey(r.Context(), key, hash)
+		if err != nil {
+			ae := NewInternalServerError(err.Error())
+			RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		if !claimed {
+			replay(w, r, rec, hash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next(recorder, r)
+
+		// the request context may be done by now, the outcome must be stored regardless
+		ctx := context.Background()
+		if recorder.statusCode >= http.StatusInternalServerError {
+			if err := store.ReleaseIdempotencyKey(ctx, key); err != nil {
+				log.Printf("releasing Idempotency-Key %s: %v", key, err)
+			}
+			return
+		}
+		if err := store.CompleteIdempotencyKey(ctx, key, recorder.statusCode, recorder.body.Bytes()); err != nil {
+			log.Printf("completing Idempotency-Key %s: %v", key, err)
+		}
+	}
+}
+
+func replay(w http.ResponseWriter, r *http.Request, rec entity.IdempotencyRecord, hash string) {
+	if rec.RequestHash != hash {
+		ae := ApiError{http.StatusUnprocessableEntity, "Idempotency-Key was used for a different request"}
+		RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	if rec.StatusCode == 0 {
+		ae := ApiError{http.StatusConflict, "a request with the same Idempotency-Key is in progress"}
+		RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(rec.StatusCode)
+	if len(rec.Body) > 0 {
+		w.Write(rec.Body)
+	}
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (o *responseRecorder) WriteHeader(statusCode int) {
+	o.statusCode = statusCode
+	o.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (o *responseRecorder) Write(b []byte) (int, error) {
+	o.body.Write(b)
+	return o.ResponseWriter.Write(b)
+}
@@ -0,0 +1,119 @@
+package apiutils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"spacetrouble/internal/pkg/entity"
+)
+
+type memIdempotencyStore struct {
+	mu   sync.Mutex
+	recs map[string]entity.IdempotencyRecord
+}
+
+func (o *memIdempotencyStore) ClaimIdempotencyKey(ctx context.Context, key, requestHash string) (entity.IdempotencyRecord, bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if rec, ok := o.recs[key]; ok {
+		return rec, false, nil
+	}
+	rec := entity.IdempotencyRecord{Key: key, RequestHash: requestHash}
+	o.recs[key] = rec
+	return rec, true, nil
+}
+
+func (o *memIdempotencyStore) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, body []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	rec := o.recs[key]
+	rec.StatusCode = statusCode
+	rec.Body = body
+	o.recs[key] = rec
+	return nil
+}
+
+func (o *memIdempotencyStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.recs, key)
+	return nil
+}
+
+func doIdempotentPost(handler http.HandlerFunc, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/bookings", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, key)
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+	return rr
+}
+
+func TestIdempotentReplaysResponse(t *testing.T) {
+	calls := 0
+	next := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		RenderResponse(r, w, http.StatusCreated, map[string]int{"call": calls})
+	}
+	handler := Idempotent(next, &memIdempotencyStore{recs: make(map[string]entity.IdempotencyRecord)})
+
+	first := doIdempotentPost(handler, "key-1", `{"a":1}`)
+	second := doIdempotentPost(handler, "key-1", `{"a":1}`)
+
+	if calls != 1 {
+		t.Errorf("expected handler to be called once but got %d", calls)
+		return
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("expected replayed response %d %s but got %d %s",
+			first.Code, first.Body.String(), second.Code, second.Body.String())
+		return
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected replay header")
+		return
+	}
+
+	other := doIdempotentPost(handler, "key-1", `{"a":2}`)
+	if other.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected %d for a different body but got %d", http.StatusUnprocessableEntity, other.Code)
+		return
+	}
+}
+
+func TestIdempotentDoesNotStoreServerErrors(t *testing.T) {
+	calls := 0
+	next := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		RenderResponse(r, w, http.StatusInternalServerError, nil)
+	}
+	handler := Idempotent(next, &memIdempotencyStore{recs: make(map[string]entity.IdempotencyRecord)})
+
+	doIdempotentPost(handler, "key-1", `{}`)
+	doIdempotentPost(handler, "key-1", `{}`)
+
+	if calls != 2 {
+		t.Errorf("expected handler to be called twice but got %d", calls)
+		return
+	}
+}
+
+func TestIdempotentInProgress(t *testing.T) {
+	store := &memIdempotencyStore{recs: make(map[string]entity.IdempotencyRecord)}
+	var inner *httptest.ResponseRecorder
+	var handler http.HandlerFunc
+	handler = Idempotent(func(w http.ResponseWriter, r *http.Request) {
+		inner = doIdempotentPost(handler, "key-1", `{}`)
+		RenderResponse(r, w, http.StatusCreated, nil)
+	}, store)
+
+	doIdempotentPost(handler, "key-1", `{}`)
+	if inner.Code != http.StatusConflict {
+		t.Errorf("expected %d while in progress but got %d", http.StatusConflict, inner.Code)
+		return
+	}
+}
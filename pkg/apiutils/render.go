@@ -32,6 +32,18 @@ func JsonDecodeBody(r *http.Request, dst interface{}) error {
 	return json.Unmarshal(body, dst)
 }
 
+// JsonDecodeOptionalBody is like JsonDecodeBody but leaves dst untouched when the body is empty.
+func JsonDecodeOptionalBody(r *http.Request, dst interface{}) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body, dst)
+}
+
 func RenderResponse(r *http.Request, w http.ResponseWriter, statusCode int, res interface{}) {
 	// TODO check request headers to determine the response type
 	// for the task only json supported
@@ -9,17 +9,26 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
+var testDbCount int32
+
+// GetTestDb connects to a new database copied from the migrated one, so that every test
+// starts from the same data without deleting any, the booking history being append-only.
 func GetTestDb() (*pgxpool.Pool, error) {
-	dsn := fmt.Sprintf("host=localhost port=%s dbname=space user=space password=secure pool_max_conns=99", os.Getenv("DB_TEST_PORT"))
 	ctx := context.Background()
-	db, err := pgxpool.Connect(ctx, dsn)
+	name := fmt.Sprintf("space_test_%d", atomic.AddInt32(&testDbCount, 1))
+	if err := execAdmin(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE space", name)); err != nil {
+		return nil, err
+	}
+	db, err := pgxpool.Connect(ctx, testDsn(name)+" pool_max_conns=99")
 	if err != nil {
 		return db, err
 	}
@@ -29,6 +38,29 @@ func GetTestDb() (*pgxpool.Pool, error) {
 	return db, err
 }
 
+// DropTestDb closes db and drops the database created for it by GetTestDb.
+func DropTestDb(db *pgxpool.Pool) error {
+	name := db.Config().ConnConfig.Database
+	db.Close()
+	return execAdmin(context.Background(), "DROP DATABASE "+name)
+}
+
+// execAdmin runs a statement from the maintenance database, as the template
+// and the dropped databases must have no connection.
+func execAdmin(ctx context.Context, q string) error {
+	conn, err := pgx.Connect(ctx, testDsn("postgres"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	_, err = conn.Exec(ctx, q)
+	return err
+}
+
+func testDsn(dbname string) string {
+	return fmt.Sprintf("host=localhost port=%s dbname=%s user=space password=secure", os.Getenv("DB_TEST_PORT"), dbname)
+}
+
 func SpinPostgresContainer(ctx context.Context, rootDir string) testcontainers.Container {
 	mountFrom := mergeMigrations(rootDir)
 	defer func() {